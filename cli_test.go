@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mkch/gg"
+)
+
+// The tests in this file run the built command against the modules in testdata/cli,
+// to keep the command line, exit codes and output layout stable for the scripts
+// wrapping the command.
+
+// cliDir is the temporary directory of the built command, removed by TestMain.
+var cliDir string
+
+var buildCommand = sync.OnceValues(func() (string, error) {
+	dir, err := os.MkdirTemp("", "goingbad-cli")
+	if err != nil {
+		return "", err
+	}
+	cliDir = dir
+	bin := filepath.Join(dir, "goingbad")
+	if output, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		return "", errors.New(string(output))
+	}
+	return bin, nil
+})
+
+// runCommand runs the built command with args in directory dir,
+// and returns the exit code and the combined output.
+func runCommand(t *testing.T, dir string, args ...string) (code int, output string) {
+	t.Helper()
+	return runCommandInput(t, dir, "", args...)
+}
+
+// runCommandInput is like runCommand, with the standard input of the command read from input.
+func runCommandInput(t *testing.T, dir, input string, args ...string) (code int, output string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the command with the go command")
+	}
+	bin, err := buildCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(out)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return 0, string(out)
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if cliDir != "" {
+		os.RemoveAll(cliDir)
+	}
+	os.Exit(code)
+}
+
+func Test_cli_exitCodes(t *testing.T) {
+	ok := gg.Must(filepath.Abs("testdata/cli/ok"))
+	broken := gg.Must(filepath.Abs("testdata/cli/broken"))
+	out := t.TempDir()
+	tests := []struct {
+		name   string
+		dir    string
+		args   []string
+		code   int
+		output string // Substring of the output.
+	}{
+		{"help", ok, []string{"-h"}, exitOK, "Exit status:"},
+		{"unknown flag", ok, []string{"-o", out, "-no-such-flag"}, exitUsage, "flag provided but not defined: -no-such-flag"},
+		{"invalid flag value", ok, []string{"-o", out, "-test-files", "never"}, exitUsage, "invalid test files policy"},
+		{"plan", ok, []string{"-plan", "./..."}, exitOK, "example.com/ok/lib"},
+		{"missing out dir", ok, []string{"./..."}, exitUsage, "-out-dir is missing"},
+		{"missing pkg file", ok, []string{"-o", out, "-pkg-file", "no-such-file"}, exitUsage, "no-such-file"},
+		{"invalid seeds", ok, []string{"-o", out, "-oie", "-seeds", "abc", "./..."}, exitUsage, "upper case letter is required"},
+		{"invalid unexported seeds", ok, []string{"-o", out, "-seeds", "ABC", "./..."}, exitUsage, "lower case letter"},
+		{"lower case seeds", ok, []string{"-o", filepath.Join(out, "seeds"), "-seeds", "abc", "./..."}, exitOK, ""},
+		{"type error", broken, []string{"-o", filepath.Join(out, "broken")}, exitFailure, "undefined"},
+		{"no package", ok, []string{"-o", filepath.Join(out, "none"), "./no-such-dir/..."}, exitFailure, ""},
+		{"strict keep", ok, []string{"-o", filepath.Join(out, "keep"), "-keep", "lib.Greting", "-strict-keep", "./..."}, exitFailure, "lib.Greeting"},
+		{"interactive overwrite", ok, []string{"-o", out, "-i", "-f", "./..."}, exitUsage, "-i can not be used with -overwrite"},
+		{"annotate without map", ok, []string{"-o", out, "-annotate", "./..."}, exitUsage, "-annotate requires -map"},
+		{"cache in place", ok, []string{"-o", out, "-cache", filepath.Join(out, "cache"), "-in-place", "./..."}, exitUsage, "-cache can not be used"},
+		{"in place", ok, []string{"-o", ".", "./..."}, exitFailure, ""},
+		{"mapdiff usage", ok, []string{"mapdiff", "a.json"}, exitUsage, "usage: goingbad mapdiff"},
+		{"mapdiff missing file", ok, []string{"mapdiff", "a.json", "b.json"}, exitFailure, ""},
+		{"verify usage", ok, []string{"verify"}, exitUsage, "usage: goingbad verify"},
+		{"verify missing manifest", ok, []string{"verify", "no-such-file"}, exitFailure, ""},
+		{"unmap usage", ok, []string{"unmap", "-o", out}, exitUsage, "usage: goingbad unmap"},
+		{"unmap missing map", ok, []string{"unmap", "-map", "no-such-file", "-o", filepath.Join(out, "unmap"), "./..."}, exitFailure, "no-such-file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, output := runCommand(t, tt.dir, tt.args...)
+			if code != tt.code {
+				t.Errorf("want exit code %v, got %v:\n%v", tt.code, code, output)
+			}
+			if !strings.Contains(output, tt.output) {
+				t.Errorf("want output containing %q, got:\n%v", tt.output, output)
+			}
+		})
+	}
+}
+
+func Test_cli_output(t *testing.T) {
+	src := gg.Must(filepath.Abs("testdata/cli/ok"))
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	manifest := filepath.Join(out, "manifest.json")
+	mapFile := filepath.Join(dir, "map.json")
+	if code, output := runCommand(t, src, "-o", out, "-map", mapFile, "-manifest", manifest, "-sha256sums", filepath.Join(out, "SHA256SUMS"), "-test-files", "copy", "./..."); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	tree, err := readTree(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SHA256SUMS", "go.mod", "lib/format.txt", "lib/lib.go", "lib/lib_test.go", "main.go", "manifest.json"}
+	if got := slices.Sorted(maps.Keys(tree)); !slices.Equal(got, want) {
+		t.Fatalf("want files %v, got %v", want, got)
+	}
+	for _, name := range []string{"lib/lib.go", "main.go"} {
+		if !strings.HasPrefix(tree[name], "// Code generated by goingbad. DO NOT EDIT.\n") {
+			t.Errorf("%v: missing header:\n%v", name, tree[name])
+		}
+	}
+	if strings.Contains(tree["lib/lib.go"], "prefix") {
+		t.Errorf("unexported function is not renamed:\n%v", tree["lib/lib.go"])
+	}
+	if _, err := os.Stat(mapFile); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(tree["SHA256SUMS"], "  main.go\n") {
+		t.Errorf("main.go is missing in SHA256SUMS:\n%v", tree["SHA256SUMS"])
+	}
+	if _, err := exec.LookPath("sha256sum"); err == nil {
+		cmd := exec.Command("sha256sum", "-c", "SHA256SUMS")
+		cmd.Dir = out
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("sha256sum -c: %v\n%s", err, output)
+		}
+	}
+
+	// Existing files are not overwritten without -overwrite, and all of them are reported.
+	if code, output := runCommand(t, src, "-o", out, "./..."); code != exitFailure {
+		t.Errorf("want exit code %v, got %v:\n%v", exitFailure, code, output)
+	} else if !strings.Contains(output, "4 output files exist") {
+		t.Errorf("want all existing files reported, got:\n%v", output)
+	}
+	// The output is verified, and reported after modified.
+	if code, output := runCommand(t, src, "verify", manifest); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	if err = os.WriteFile(filepath.Join(out, "main.go"), []byte("package main\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	code, output := runCommand(t, src, "verify", "-dir", out, manifest)
+	if code != exitMismatch {
+		t.Errorf("want exit code %v, got %v:\n%v", exitMismatch, code, output)
+	}
+	if output != "modified\tmain.go\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	// Maps are compared.
+	code, output = runCommand(t, src, "mapdiff", mapFile, mapFile)
+	if code != exitOK || !strings.HasPrefix(output, "0 changed, 0 added, 0 removed") {
+		t.Errorf("want exit code %v without changes, got %v:\n%v", exitOK, code, output)
+	}
+}
+
+func Test_cli_unmap(t *testing.T) {
+	src := gg.Must(filepath.Abs("testdata/cli/ok"))
+	dir := t.TempDir()
+	obfuscated := filepath.Join(dir, "obfuscated")
+	mapFile := filepath.Join(dir, "map.json")
+	if code, output := runCommand(t, src, "-o", obfuscated, "-map", mapFile, "-oie", "-test-files", "copy", "./..."); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	out := filepath.Join(dir, "out")
+	if code, output := runCommand(t, obfuscated, "unmap", "-map", mapFile, "-o", out, "./lib"); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	tree, err := readTree(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"func Greeting(name string) string {\n\treturn prefix() + name\n}", "func prefix() string {\n\treturn format\n}"} {
+		if !strings.Contains(tree["lib/lib.go"], want) {
+			t.Errorf("want lib/lib.go containing %q, got:\n%v", want, tree["lib/lib.go"])
+		}
+	}
+	if !strings.Contains(tree["main.go"], "lib.Greeting(") {
+		t.Errorf("reference to the restored package is not restored:\n%v", tree["main.go"])
+	}
+	cmd := exec.Command("go", "vet", "./...")
+	cmd.Dir = out
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet: %v\n%s", err, output)
+	}
+	// Existing files are not overwritten without -overwrite.
+	if code, output := runCommand(t, obfuscated, "unmap", "-map", mapFile, "-o", out, "./lib"); code != exitFailure {
+		t.Errorf("want exit code %v, got %v:\n%v", exitFailure, code, output)
+	}
+	if code, output := runCommand(t, obfuscated, "unmap", "-map", mapFile, "-o", out, "-overwrite", "./lib"); code != exitOK {
+		t.Errorf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+}
+
+func Test_cli_conflicts(t *testing.T) {
+	src := gg.Must(filepath.Abs("testdata/cli/ok"))
+	out := t.TempDir()
+	messagePackage := filepath.Join(out, "msgs", "msgs.go")
+	if err := os.MkdirAll(filepath.Dir(messagePackage), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(messagePackage, []byte("package msgs\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	mapFile := filepath.Join(out, "map.json")
+	if err := os.WriteFile(mapFile, []byte("{}\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	code, output := runCommand(t, src, "-o", out, "-message-package", "msgs", "-map", mapFile, "./...")
+	if code != exitFailure {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitFailure, code, output)
+	}
+	if !strings.Contains(output, "2 output files exist") {
+		t.Errorf("want all existing files reported, got:\n%v", output)
+	}
+	// Nothing is written.
+	tree, err := readTree(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"map.json", "msgs/msgs.go"}; !slices.Equal(slices.Sorted(maps.Keys(tree)), want) {
+		t.Errorf("want files %v, got %v", want, slices.Sorted(maps.Keys(tree)))
+	}
+	if tree["msgs/msgs.go"] != "package msgs\n" || tree["map.json"] != "{}\n" {
+		t.Errorf("existing files are modified")
+	}
+}
+
+func Test_cli_postProcessConflicts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("touch is not available")
+	}
+	src := gg.Must(filepath.Abs("testdata/cli/ok"))
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	manifest := filepath.Join(dir, "manifest.json")
+	// The files created by post-processing are written files.
+	if code, output := runCommand(t, src, "-o", out, "-manifest", manifest, "-post-package", "touch {{.Dir}}/stamp", "./..."); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"path": "stamp"`, `"path": "lib/stamp"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("want %q in manifest, got:\n%s", want, data)
+		}
+	}
+	// Changing an existing file by post-processing is a conflict.
+	out = filepath.Join(dir, "out2")
+	stamp := filepath.Join(out, "stamp")
+	if err := os.MkdirAll(out, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stamp, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(stamp, old, old); err != nil {
+		t.Fatal(err)
+	}
+	code, output := runCommand(t, src, "-o", out, "-post-package", "touch {{.Dir}}/stamp", "./...")
+	if code != exitFailure || !strings.Contains(output, "changed by post-processing") {
+		t.Errorf("want exit code %v reporting the changed file, got %v:\n%v", exitFailure, code, output)
+	}
+	if code, output := runCommand(t, src, "-o", out, "-overwrite", "-post-package", "touch {{.Dir}}/stamp", "./..."); code != exitOK {
+		t.Errorf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+}
+
+func Test_cli_skipConflicts(t *testing.T) {
+	src := gg.Must(filepath.Abs("testdata/cli/ok"))
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if code, output := runCommand(t, src, "-o", out, "./..."); code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	if err := os.Remove(filepath.Join(out, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	// The main package is written, and the files of lib and go.mod are kept.
+	manifest := filepath.Join(dir, "manifest.json")
+	code, output := runCommandInput(t, src, "s\n", "-o", out, "-i", "-manifest", manifest, "./...")
+	if code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	if _, err := os.Stat(filepath.Join(out, "main.go")); err != nil {
+		t.Error(err)
+	}
+	// The kept files are in the manifest.
+	if code, output := runCommand(t, src, "verify", "-dir", out, manifest); code != exitOK {
+		t.Errorf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+}
+
+func Test_cli_quarantine(t *testing.T) {
+	src := gg.Must(filepath.Abs("testdata/cli/quarantine"))
+	out := t.TempDir()
+	// Package a is copied verbatim, and b implements its interface and converts to its struct type.
+	code, output := runCommand(t, src, "-o", out, "-oie", "-quarantine", "unsafe", "./...")
+	if code != exitOK {
+		t.Fatalf("want exit code %v, got %v:\n%v", exitOK, code, output)
+	}
+	if output, err := exec.Command("go", "-C", out, "build", "./...").CombinedOutput(); err != nil {
+		t.Fatalf("output does not build: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/compat"
+	"github.com/mkch/goingbad/internal/flags"
+	"github.com/mkch/goingbad/internal/pattern"
+	"golang.org/x/tools/go/packages"
+)
+
+// writesAliases returns whether the aliases of renamed exports are written for pkg,
+// which is an importable package matching -compat-aliases.
+func writesAliases(pkg *packages.Package) bool {
+	if pkg.Name == "main" || strings.HasSuffix(pkg.Name, "_test") {
+		return false
+	}
+	for _, p := range cmdArgs.CompatAliases {
+		if pattern.Match(p, pkg.PkgPath, pkg.Dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAliases writes the aliases of the renamed exports of pkg to destPkgDir.
+// Nothing is written if no export of pkg is renamed. The security-critical exports,
+// which are in secure or -secure, are not aliased.
+//
+// If prev, the file of the aliases written by a previous run, is not nil, it is
+// written with the new aliases appended.
+func writeAliases(pkg *packages.Package, destPkgDir string, newNames map[types.Object]string, secure gg.Set[types.Object], prev *ast.File) (err error) {
+	decls, skipped := compat.Decls(pkg.Types, newNames, func(obj types.Object) bool {
+		return secure.Contains(obj) || cmdArgs.SecureNames.Contains(pkg.PkgPath, obj.Name())
+	})
+	for _, s := range skipped {
+		slog.Warn("renamed export has no alias", "pkg", pkg.PkgPath, "name", s.Object.Name(), "reason", s.Reason)
+	}
+	dest := filepath.Join(destPkgDir, flags.CompatAliasesFile)
+	var src []byte
+	if prev != nil {
+		if src, err = formatFile(pkg.Fset, prev, dest); err != nil {
+			return
+		}
+		if src, err = format.Source(append(src, decls...)); err != nil {
+			return
+		}
+	} else {
+		if decls == nil {
+			return
+		}
+		src = append(compat.Header(pkg.Types), decls...)
+		fset := token.NewFileSet()
+		var f *ast.File
+		if f, err = parser.ParseFile(fset, dest, src, parser.ParseComments); err != nil {
+			return
+		}
+		if src, err = formatFile(fset, f, dest); err != nil {
+			return
+		}
+	}
+	slog.Info("writing aliases...\t", "path", dest)
+	w, err := createFile(dest)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(src)
+	return errors.Join(err, w.Close())
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// conflicts is the existing files which would be overwritten by the output.
+type conflicts struct {
+	pkgs  []*packages.Package // Packages writing existing files.
+	files [][]string          // The files of pkgs.
+	mods  []string            // Module files.
+	// Files of the whole run, such as the manifest and the name table, which can not be skipped.
+	others []string
+}
+
+// count returns the number of the existing files.
+func (c *conflicts) count() (n int) {
+	for _, files := range c.files {
+		n += len(files)
+	}
+	return n + len(c.mods) + len(c.others)
+}
+
+// findConflicts returns the existing files which would be overwritten by writing pkgs
+// with write, and mods, the module files. The files of a package are the files
+// recorded by write with dryRun set.
+// The files written by -post-file and -post-package commands are checked by postProcess.
+func findConflicts(pkgs []*packages.Package, write func(pkg *packages.Package) error, mods []moduleFile) (*conflicts, error) {
+	c := &conflicts{}
+	for _, pkg := range pkgs {
+		files, err := outputFiles(pkg, write)
+		if err != nil {
+			return nil, err
+		}
+		var existing []string
+		for _, file := range files {
+			if exists(file) {
+				existing = append(existing, file)
+			}
+		}
+		if len(existing) > 0 {
+			c.pkgs = append(c.pkgs, pkg)
+			c.files = append(c.files, existing)
+		}
+	}
+	for _, mod := range mods {
+		if exists(mod.to) {
+			c.mods = append(c.mods, mod.to)
+		}
+	}
+	others := []string{cmdArgs.MapFile, cmdArgs.ManifestFile, cmdArgs.SumsFile, cmdArgs.MessagesFile, cmdArgs.ReportFile}
+	if cmdArgs.NameTable != "" {
+		others = append(others, nameTableFile())
+	}
+	if cmdArgs.MessagePackage != "" {
+		others = append(others, messagePackageFile())
+	}
+	for _, file := range others {
+		if file != "" && exists(file) {
+			c.others = append(c.others, file)
+		}
+	}
+	return c, nil
+}
+
+// exists returns whether file exists.
+func exists(file string) bool {
+	_, err := os.Lstat(file)
+	return err == nil
+}
+
+// outputFiles returns the files written for pkg by write, which is called with dryRun set.
+// The logs of write are discarded, which are written again when pkg is written.
+func outputFiles(pkg *packages.Package, write func(pkg *packages.Package) error) (files []string, err error) {
+	level := slog.SetLogLoggerLevel(slog.LevelError + 1)
+	first := len(written)
+	dryRun = true
+	defer func() {
+		dryRun = false
+		written = written[:first]
+		slog.SetLogLoggerLevel(level)
+	}()
+	err = write(pkg)
+	return slices.Clone(written[first:]), err
+}
+
+// conflictError is the error of existing output files, for which nothing is written.
+type conflictError struct{ error }
+
+func (err conflictError) Unwrap() error { return err.error }
+
+// conflictAction is the action on the existing output files chosen with -i.
+type conflictAction int
+
+const (
+	abortConflicts     conflictAction = iota // Write nothing.
+	overwriteConflicts                       // Overwrite the existing files.
+	skipConflicts                            // Skip the packages writing existing files, and keep the module files.
+)
+
+// promptConflicts asks the action on n existing output files on w, and reads the answer from r.
+// The prompt is repeated until a valid answer. The action is abortConflicts at the end of r.
+func promptConflicts(r io.Reader, w io.Writer, n int) (conflictAction, error) {
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "%d output %v. Overwrite them (o), skip the packages writing them (s), or abort (a)? [a] ",
+			n, gg.If(n > 1, "files exist", "file exists"))
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return abortConflicts, scanner.Err()
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "o", "overwrite":
+			return overwriteConflicts, nil
+		case "s", "skip":
+			return skipConflicts, nil
+		case "", "a", "abort":
+			return abortConflicts, nil
+		}
+	}
+}
+
+// resolveConflicts reports c, and returns the packages to skip and the existing files to keep,
+// of the skipped packages and the modules, as chosen with -i. An error is returned if the output is aborted. -overwrite is set if the
+// files are chosen to be overwritten.
+func resolveConflicts(c *conflicts) (skipped gg.Set[*packages.Package], kept gg.Set[string], err error) {
+	for i, pkg := range c.pkgs {
+		for _, file := range c.files[i] {
+			slog.Error("output file exists", "pkg", pkg.PkgPath, "path", file)
+		}
+	}
+	for _, file := range slices.Concat(c.mods, c.others) {
+		slog.Error("output file exists", "path", file)
+	}
+	n := c.count()
+	if !cmdArgs.Interactive {
+		return nil, nil, conflictError{fmt.Errorf("%d output %v, use -overwrite to overwrite them or -i to choose",
+			n, gg.If(n > 1, "files exist", "file exists"))}
+	}
+	action, err := promptConflicts(os.Stdin, os.Stderr, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch action {
+	case overwriteConflicts:
+		cmdArgs.Force = true
+		return nil, nil, nil
+	case skipConflicts:
+		if len(c.others) > 0 {
+			return nil, nil, conflictError{fmt.Errorf("existing %v can not be skipped", strings.Join(c.others, ", "))}
+		}
+		skipped = make(gg.Set[*packages.Package])
+		for _, pkg := range c.pkgs {
+			skipped.Add(pkg)
+		}
+		kept = make(gg.Set[string])
+		for _, file := range slices.Concat(slices.Concat(c.files...), c.mods) {
+			kept.Add(file)
+		}
+		return skipped, kept, nil
+	}
+	return nil, nil, conflictError{errors.New("aborted")}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+
+	"golang.org/x/tools/imports"
+)
+
+// importsOptions are the options of goimports without adding or removing imports.
+var importsOptions = &imports.Options{Comments: true, TabIndent: true, TabWidth: 8, FormatOnly: true}
+
+// formatFile formats f with the "DO NOT EDIT" header.
+// The result is stable under gofmt and goimports: import declarations
+// are merged, sorted and grouped the way goimports does.
+func formatFile(fset *token.FileSet, f *ast.File, filename string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := doNotEdit(&buf); err != nil {
+		return nil, err
+	}
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return imports.Process(filename, buf.Bytes(), importsOptions)
+}
+
+// checkFormat returns an error if gofmt or goimports would change src.
+func checkFormat(filename string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(formatted, src) {
+		return fmt.Errorf("%v: output is changed by gofmt", filename)
+	}
+	if formatted, err = imports.Process(filename, src, importsOptions); err != nil {
+		return err
+	}
+	if !bytes.Equal(formatted, src) {
+		return fmt.Errorf("%v: output is changed by goimports", filename)
+	}
+	return nil
+}
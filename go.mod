@@ -1,14 +1,14 @@
 module github.com/mkch/goingbad
 
-go 1.24.0
+go 1.25.0
 
 require (
 	github.com/mkch/gg v0.0.0-20250504154157-7692da2ff454
-	golang.org/x/tools v0.32.0
+	golang.org/x/tools v0.44.0
 )
 
 require (
 	github.com/mkch/iter2 v0.0.0-20250422043347-0a8d32207b63
-	golang.org/x/mod v0.24.0 // indirect
-	golang.org/x/sync v0.13.0 // indirect
+	golang.org/x/mod v0.35.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
 )
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/cache"
+	"github.com/mkch/goingbad/internal/mapping"
+	"github.com/mkch/goingbad/internal/sibling"
+	"golang.org/x/tools/go/packages"
+)
+
+// globalKey returns the digest of the inputs shared by all packages: the flags
+// and their input files, the command itself, and shared, the names and types kept in all packages.
+func globalKey(shared []string) (string, error) {
+	fingerprint, err := cmdArgs.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+	h := cache.NewHasher(fingerprint)
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if err = h.File(exe); err != nil {
+		return "", err
+	}
+	h.Strings(shared)
+	return h.Key(), nil
+}
+
+// packageKeys returns the cache keys of pkgs.
+//
+// The key of a package covers global, its files, the go.mod and go.sum of its module,
+// the names kept[pkg] kept by other packages, and the keys of the packages of pkgs it
+// imports. The new names of the exported declarations of a package depend on nothing
+// else, so the keys of the packages whose objects are referred to by refs[pkg], the
+// paths of the packages referred to outside identifiers, are also covered.
+func packageKeys(pkgs []*packages.Package, global string, kept, refs map[*packages.Package][]string) (map[*packages.Package]string, error) {
+	byPath := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		byPath[pkg.PkgPath] = pkg
+	}
+	// Keys covering the imports only. Refs may be cyclic.
+	importKeys := make(map[*packages.Package]string)
+	var importKey func(pkg *packages.Package) (string, error)
+	importKey = func(pkg *packages.Package) (string, error) {
+		if key, ok := importKeys[pkg]; ok {
+			return key, nil
+		}
+		h := cache.NewHasher(global)
+		h.String(pkg.ID)
+		files := slices.Concat(pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles, pkg.EmbedFiles, pkg.IgnoredFiles)
+		tests, err := filepath.Glob(filepath.Join(pkg.Dir, "*_test.go"))
+		if err != nil {
+			return "", err
+		}
+		files = append(files, tests...)
+		if pkg.Module != nil && pkg.Module.GoMod != "" {
+			files = append(files, pkg.Module.GoMod, filepath.Join(filepath.Dir(pkg.Module.GoMod), "go.sum"))
+		}
+		slices.Sort(files)
+		for _, file := range slices.Compact(files) {
+			if err := h.File(file); err != nil {
+				return "", err
+			}
+		}
+		h.Strings(kept[pkg])
+		var imports []string
+		if pkg.Types != nil {
+			for _, imported := range pkg.Types.Imports() {
+				if dep := byPath[imported.Path()]; dep != nil && dep != pkg {
+					key, err := importKey(dep)
+					if err != nil {
+						return "", err
+					}
+					imports = append(imports, key)
+				} else {
+					imports = append(imports, imported.Path())
+				}
+			}
+		}
+		h.Strings(imports)
+		importKeys[pkg] = h.Key()
+		return importKeys[pkg], nil
+	}
+	keys := make(map[*packages.Package]string)
+	for _, pkg := range pkgs {
+		key, err := importKey(pkg)
+		if err != nil {
+			return nil, err
+		}
+		h := cache.NewHasher(key)
+		var referred []string
+		for _, path := range refs[pkg] {
+			if dep := byPath[path]; dep != nil && dep != pkg {
+				if key, err = importKey(dep); err != nil {
+					return nil, err
+				}
+				referred = append(referred, key)
+			}
+		}
+		h.Strings(referred)
+		keys[pkg] = h.Key()
+	}
+	return keys, nil
+}
+
+// packageExports returns the exported identifiers of pkg in renamedExports.
+func packageExports(pkg *packages.Package, renamedExports map[token.Pos]string) (exports []cache.Export) {
+	for id := range pkg.TypesInfo.Defs {
+		if name, ok := renamedExports[id.Pos()]; ok {
+			position := pkg.Fset.Position(id.Pos())
+			exports = append(exports, cache.Export{File: filepath.Base(position.Filename), Offset: position.Offset, Name: name})
+		}
+	}
+	slices.SortFunc(exports, func(a, b cache.Export) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Offset, b.Offset))
+	})
+	return
+}
+
+// restoreExports adds the exported identifiers of pkg renamed in entry to renamedExports,
+// newNames and the names of the siblings, as renaming pkg would.
+func restoreExports(pkg *packages.Package, entry *cache.Entry, renamedExports map[token.Pos]string, newNames map[types.Object]string,
+	names *sibling.Names, exportedNames map[string]map[string]string) error {
+	files := make(map[string]*token.File)
+	for _, f := range pkg.Syntax {
+		tf := pkg.Fset.File(f.Pos())
+		files[filepath.Base(tf.Name())] = tf
+	}
+	defs := make(map[token.Pos]*ast.Ident)
+	for id := range pkg.TypesInfo.Defs {
+		defs[id.Pos()] = id
+	}
+	for _, export := range entry.Exports {
+		tf := files[export.File]
+		if tf == nil || export.Offset < 0 || export.Offset > tf.Size() {
+			return fmt.Errorf("invalid cache entry of package %v", pkg.PkgPath)
+		}
+		pos := tf.Pos(export.Offset)
+		renamedExports[pos] = export.Name
+		if id := defs[pos]; id != nil {
+			if obj := pkg.TypesInfo.Defs[id]; obj != nil {
+				newNames[obj] = export.Name
+				addSiblingName(pkg, names, exportedNames, obj, export.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreFiles writes the files of entry to the output directory.
+// If dryRun is set, the files are only recorded.
+func restoreFiles(entry *cache.Entry) error {
+	for _, file := range entry.Files {
+		dest := filepath.Join(cmdArgs.OutDir, filepath.FromSlash(file.Path))
+		if dryRun {
+			written = append(written, dest)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|gg.If(cmdArgs.Force, os.O_TRUNC, os.O_EXCL), file.Mode)
+		if err != nil {
+			return err
+		}
+		written = append(written, dest)
+		_, err = f.Write(file.Data)
+		if err = errors.Join(err, f.Close()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheEntry returns the entry of pkg with key, which wrote files and renamed entries.
+// Nil is returned if some of files are outside the output directory, which are not cached.
+func cacheEntry(pkg *packages.Package, key string, files []string, entries []mapping.Entry, renamedExports map[token.Pos]string) (*cache.Entry, error) {
+	entry := &cache.Entry{Package: pkg.ID, Key: key, Entries: entries}
+	if pkg.TypesInfo != nil {
+		entry.Exports = packageExports(pkg, renamedExports)
+	}
+	for _, file := range files {
+		rel, err := filepath.Rel(cmdArgs.OutDir, file)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, nil
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entry.Files = append(entry.Files, cache.File{Path: filepath.ToSlash(rel), Mode: info.Mode().Perm(), Data: data})
+	}
+	return entry, nil
+}
+
+// lookupCache opens the cache of -cache, and returns the keys of pkgs and the entries
+// of the unchanged packages. See packageKeys for kept and refs, and globalKey for shared.
+func lookupCache(pkgs []*packages.Package, kept, refs map[*packages.Package][]string, shared []string) (
+	c *cache.Cache, keys map[*packages.Package]string, entries map[*packages.Package]*cache.Entry, err error) {
+	if c, err = cache.Open(cmdArgs.CacheDir); err != nil {
+		return
+	}
+	global, err := globalKey(shared)
+	if err != nil {
+		return
+	}
+	if keys, err = packageKeys(pkgs, global, kept, refs); err != nil {
+		return
+	}
+	entries = make(map[*packages.Package]*cache.Entry)
+	for _, pkg := range pkgs {
+		var entry *cache.Entry
+		if entry, err = c.Get(pkg.ID, keys[pkg]); err != nil {
+			return
+		}
+		if entry != nil {
+			entries[pkg] = entry
+		}
+	}
+	return
+}
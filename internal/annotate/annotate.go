@@ -0,0 +1,32 @@
+// Package annotate marks renamed identifiers with the indexes of their entries
+// in the map file, so specific renames can be verified in large outputs.
+package annotate
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"slices"
+)
+
+// Marker returns the marker comment of the map entry at index.
+func Marker(index int) string {
+	return fmt.Sprintf("/*g2b:%d*/", index)
+}
+
+// Annotate adds the marker comments after the identifiers of f in indexes,
+// which maps identifiers to the indexes of their map entries.
+func Annotate(f *ast.File, indexes map[*ast.Ident]int) {
+	ast.Inspect(f, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			if i, ok := indexes[id]; ok {
+				// A comment at the position of a token is printed right after the token.
+				// The end of id is not used, because the new name may be longer than the original.
+				f.Comments = append(f.Comments, &ast.CommentGroup{List: []*ast.Comment{{Slash: id.Pos(), Text: Marker(i)}}})
+			}
+		}
+		return true
+	})
+	// The printer requires comments in the order of positions.
+	slices.SortStableFunc(f.Comments, func(a, b *ast.CommentGroup) int { return cmp.Compare(a.Pos(), b.Pos()) })
+}
@@ -0,0 +1,53 @@
+package annotate
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func Test_Annotate(t *testing.T) {
+	const src = `package a
+
+// b is renamed.
+func b(c int) int {
+	return c+1 // c is renamed.
+}
+`
+	const want = `package a
+
+// b is renamed.
+func b /*g2b:0*/ (cc /*g2b:1*/ int) int {
+	return cc /*g2b:1*/ + 1 // c is renamed.
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := make(map[*ast.Ident]int)
+	ast.Inspect(f, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			switch id.Name {
+			case "b":
+				indexes[id] = 0
+			case "c":
+				id.Name = "cc" // Renamed to a longer name.
+				indexes[id] = 1
+			}
+		}
+		return true
+	})
+	Annotate(f, indexes)
+	var got strings.Builder
+	if err := format.Node(&got, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, got.String())
+	}
+}
@@ -0,0 +1,140 @@
+// Package asmstub finds the functions declared without bodies in go files and
+// implemented in the assembly files of their packages, such as
+//
+//	func add(x, y int64) int64
+//
+// in stubs.go, implemented in add_amd64.s by
+//
+//	TEXT ·add(SB), NOSPLIT, $0-24
+//
+// The assembly refers to the functions by their names, and to the parameters and
+// results by their names too, which are checked by go vet.
+package asmstub
+
+import (
+	"bufio"
+	"cmp"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// reText matches the TEXT directives defining the functions of the package being assembled,
+// whose symbols are the names prefixed with a middle dot, optionally by the package path too:
+//
+//	TEXT ·add(SB), NOSPLIT, $0-24
+//	TEXT example.com∕asm·add<ABIInternal>(SB), NOSPLIT, $0-24
+var reText = regexp.MustCompile(`^\s*TEXT\s+[^\s·(]*·([\pL_][\pL\p{Nd}_]*)(?:<\w+>)?\(SB\)`)
+
+// Symbols returns the names of the functions defined in asmFiles.
+func Symbols(asmFiles []string) (gg.Set[string], error) {
+	result := make(gg.Set[string])
+	for _, file := range asmFiles {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if m := reText.FindStringSubmatch(scanner.Text()); m != nil {
+				result.Add(m[1])
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Stub is a function declared without body and implemented in assembly.
+type Stub struct {
+	Name string
+	Pos  token.Position // Of the first declaration.
+}
+
+// Find returns the stubs of pkg sorted by names: the package level functions declared without
+// bodies whose symbols are defined in the assembly files of pkg. The files excluded from the
+// build, such as the ones for other platforms, are included.
+func Find(pkg *packages.Package) ([]Stub, error) {
+	var asmFiles []string
+	var ignored []string
+	for _, file := range slices.Concat(pkg.OtherFiles, pkg.IgnoredFiles) {
+		switch ext := filepath.Ext(file); {
+		case strings.EqualFold(ext, ".s"):
+			asmFiles = append(asmFiles, file)
+		case ext == ".go" && slices.Contains(pkg.IgnoredFiles, file):
+			ignored = append(ignored, file)
+		}
+	}
+	if len(asmFiles) == 0 {
+		return nil, nil
+	}
+	symbols, err := Symbols(asmFiles)
+	if err != nil {
+		return nil, err
+	}
+	files := slices.Clone(pkg.Syntax)
+	for _, file := range ignored {
+		f, err := parser.ParseFile(pkg.Fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	found := make(map[string]Stub)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Body == nil && symbols.Contains(fn.Name.Name) {
+				if _, ok := found[fn.Name.Name]; !ok {
+					found[fn.Name.Name] = Stub{fn.Name.Name, pkg.Fset.Position(fn.Name.Pos())}
+				}
+			}
+		}
+	}
+	return slices.SortedFunc(maps.Values(found), func(a, b Stub) int { return cmp.Compare(a.Name, b.Name) }), nil
+}
+
+// KeptIdents returns the identifiers in files which must keep their names for stubs:
+// the names of the package level functions named after stubs, including the ones with
+// bodies for other platforms, and the parameters and results of those without bodies.
+func KeptIdents(files []*ast.File, stubs []Stub) gg.Set[*ast.Ident] {
+	result := make(gg.Set[*ast.Ident])
+	if len(stubs) == 0 {
+		return result
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !slices.ContainsFunc(stubs, func(s Stub) bool { return s.Name == fn.Name.Name }) {
+				continue
+			}
+			result.Add(fn.Name)
+			if fn.Body != nil {
+				continue
+			}
+			for _, fields := range []*ast.FieldList{fn.Type.Params, fn.Type.Results} {
+				if fields == nil {
+					continue
+				}
+				for _, field := range fields.List {
+					for _, name := range field.Names {
+						result.Add(name)
+					}
+				}
+			}
+		}
+	}
+	return result
+}
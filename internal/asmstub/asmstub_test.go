@@ -0,0 +1,39 @@
+package asmstub
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mkch/iter2"
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Find(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{
+		Fset:         fset,
+		Syntax:       []*ast.File{f},
+		OtherFiles:   []string{"testdata/a_amd64.s"},
+		IgnoredFiles: []string{"testdata/a_arm64.go", "testdata/a_arm64.s"},
+	}
+	stubs, err := Find(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Collect(iter2.Map(slices.Values(stubs), func(s Stub) string { return s.Name + "@" + s.Pos.String() }))
+	if want := []string{"add@testdata/a.go:4:6", "neg@testdata/a_arm64.go:3:6"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	kept := slices.Sorted(iter2.Map(maps.Keys(KeptIdents(pkg.Syntax, stubs)), func(id *ast.Ident) string { return id.Name }))
+	if want := []string{"add", "neg", "sum", "x", "y"}; !slices.Equal(kept, want) {
+		t.Fatalf("want %v, got %v", want, kept)
+	}
+}
@@ -0,0 +1,12 @@
+package a
+
+// add is implemented in a_amd64.s.
+func add(x, y int64) (sum int64)
+
+// sub is linked from elsewhere.
+func sub(x, y int64) int64
+
+// neg is implemented in a_arm64.s on arm64.
+func neg(x int64) int64 { return -x }
+
+func mul(x, y int64) int64 { return x * y }
@@ -0,0 +1,8 @@
+#include "textflag.h"
+
+// func add(x, y int64) (sum int64)
+TEXT ·add(SB), NOSPLIT, $0-24
+	MOVQ x+0(FP), AX
+	ADDQ y+8(FP), AX
+	MOVQ AX, sum+16(FP)
+	RET
@@ -0,0 +1,3 @@
+package a
+
+func neg(x int64) int64
@@ -0,0 +1,8 @@
+#include "textflag.h"
+
+// func neg(x int64) int64
+TEXT example.com∕a·neg<ABIInternal>(SB), NOSPLIT, $0-16
+	MOVD x+0(FP), R0
+	NEG R0, R0
+	MOVD R0, ret+8(FP)
+	RET
@@ -0,0 +1,150 @@
+// Package cache stores the output of packages by keys, which are digests of everything
+// the output of a package depends on, so unchanged packages are not obfuscated again
+// in incremental runs.
+//
+// The key of a package covers its files and the keys of the loaded packages it imports,
+// so a change invalidates the changed package and all its reverse dependencies.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/mkch/goingbad/schema"
+)
+
+// File is an output file of a package.
+type File struct {
+	Path string      `json:"path"` // Slash separated path relative to the output directory.
+	Mode fs.FileMode `json:"mode"`
+	Data []byte      `json:"data"`
+}
+
+// Export is an exported identifier renamed in a package, which the importers refer to.
+type Export struct {
+	File   string `json:"file"` // Base name of the go file.
+	Offset int    `json:"offset"`
+	Name   string `json:"name"` // The new name.
+}
+
+// Entry is the cached output of a package.
+type Entry struct {
+	Package string                `json:"package"` // ID of the package, see [golang.org/x/tools/go/packages.Package].
+	Key     string                `json:"key"`
+	Files   []File                `json:"files"`
+	Entries []schema.MappingEntry `json:"entries"` // Renamed identifiers.
+	Exports []Export              `json:"exports"`
+}
+
+// Cache is a directory of entries.
+type Cache struct {
+	dir string
+}
+
+// Open returns the cache in directory dir, which is created if not exists.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Cache{dir}, nil
+}
+
+// file returns the path of the entry of package id.
+func (c *Cache) file(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the entry of package id if its key is key, or nil.
+func (c *Cache) Get(id, key string) (*Entry, error) {
+	data, err := os.ReadFile(c.file(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, nil // Corrupted entries are overwritten.
+	}
+	if entry.Package != id || entry.Key != key {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put stores entry, replacing the previous entry of the same package.
+func (c *Cache) Put(entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file and renamed, so interrupted runs leave no partial entries.
+	f, err := os.CreateTemp(c.dir, "entry-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.file(entry.Package))
+}
+
+// Hasher computes keys.
+type Hasher struct {
+	h hash.Hash
+}
+
+// NewHasher returns a Hasher whose keys start with global, the digest of the inputs
+// shared by all packages.
+func NewHasher(global string) *Hasher {
+	h := &Hasher{sha256.New()}
+	h.String(global)
+	return h
+}
+
+// String adds s to the key.
+func (h *Hasher) String(s string) {
+	// Length prefixed, so the concatenations of different strings differ.
+	h.h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(s))))
+	io.WriteString(h.h, s)
+}
+
+// Strings adds the sorted strs to the key.
+func (h *Hasher) Strings(strs []string) {
+	h.String(strconv.Itoa(len(strs)))
+	for _, s := range slices.Sorted(slices.Values(strs)) {
+		h.String(s)
+	}
+}
+
+// File adds the name and content of file to the key. A missing file is added as missing.
+func (h *Hasher) File(file string) error {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		h.String(file + "\x00missing")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	h.String(file)
+	h.String(string(data))
+	return nil
+}
+
+// Key returns the hex encoded key.
+func (h *Hasher) Key() string {
+	return hex.EncodeToString(h.h.Sum(nil))
+}
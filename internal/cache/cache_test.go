@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mkch/goingbad/schema"
+)
+
+func Test_Cache(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := c.Get("example.com/a", "k1"); err != nil || entry != nil {
+		t.Fatalf("want no entry, got %v %v", entry, err)
+	}
+	entry := &Entry{
+		Package: "example.com/a",
+		Key:     "k1",
+		Files:   []File{{Path: "a/a.go", Mode: 0644, Data: []byte("package a\n")}},
+		Entries: []schema.MappingEntry{{Package: "example.com/a", Key: "T", Old: "T", New: "A"}},
+	}
+	if err = c.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Get("example.com/a", "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entry) {
+		t.Fatalf("want %+v, got %+v", entry, got)
+	}
+	if got, err = c.Get("example.com/a", "k2"); err != nil || got != nil {
+		t.Fatalf("want no entry of another key, got %v %v", got, err)
+	}
+	if got, err = c.Get("example.com/b", "k1"); err != nil || got != nil {
+		t.Fatalf("want no entry of another package, got %v %v", got, err)
+	}
+	if err = os.WriteFile(c.file("example.com/a"), []byte("{"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = c.Get("example.com/a", "k1"); err != nil || got != nil {
+		t.Fatalf("want no corrupted entry, got %v %v", got, err)
+	}
+}
+
+func Test_Hasher(t *testing.T) {
+	key := func(f func(h *Hasher)) string {
+		h := NewHasher("global")
+		f(h)
+		return h.Key()
+	}
+	if key(func(h *Hasher) { h.String("ab"); h.String("c") }) == key(func(h *Hasher) { h.String("a"); h.String("bc") }) {
+		t.Error("strings are not separated")
+	}
+	if key(func(h *Hasher) { h.Strings([]string{"a", "b"}) }) != key(func(h *Hasher) { h.Strings([]string{"b", "a"}) }) {
+		t.Error("order of strings matters")
+	}
+	if NewHasher("a").Key() == NewHasher("b").Key() {
+		t.Error("global is ignored")
+	}
+
+	file := filepath.Join(t.TempDir(), "a.go")
+	fileKey := func() string {
+		return key(func(h *Hasher) {
+			if err := h.File(file); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	missing := fileKey()
+	if err := os.WriteFile(file, []byte("package a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	v1 := fileKey()
+	if err := os.WriteFile(file, []byte("package a // edited\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if v2 := fileKey(); missing == v1 || v1 == v2 {
+		t.Error("file changes are not detected")
+	}
+}
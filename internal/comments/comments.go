@@ -3,8 +3,11 @@ package comments
 
 import (
 	"go/ast"
+	"go/token"
 	"regexp"
 	"slices"
+
+	"github.com/mkch/goingbad/internal/generated"
 )
 
 // https://tip.golang.org/doc/comment#syntax (without line directive)
@@ -13,20 +16,45 @@ var reDirective = regexp.MustCompile(`^//(extern |export |[a-z0-9]+:[a-z0-9])`)
 // https://pkg.go.dev/cmd/compile#hdr-Compiler_Directives
 var reLineDirective = regexp.MustCompile(`^(//|/\*)line .*:.*$`)
 
-func isDirective(comment string) bool {
-	return reDirective.MatchString(comment) || reLineDirective.MatchString(comment)
+// Directives of goingbad itself, such as //goingbad:secure.
+// They are not meant for the compiler and are trimmed like other comments,
+// except generated.Directive, which marks the files generated by goingbad.
+var reToolDirective = regexp.MustCompile(`^//goingbad:`)
+
+// IsDirective returns whether comment is a directive for the compiler or other tools,
+// such as //go:noinline and //export. The directives of goingbad itself are not.
+// Line directives are not attached to declarations, see isKept.
+func IsDirective(comment string) bool {
+	return reDirective.MatchString(comment) && !reToolDirective.MatchString(comment)
+}
+
+// isKept returns whether comment is kept when comments are trimmed,
+// which is the case for directives, line directives and generated.Directive.
+func isKept(comment string) bool {
+	return IsDirective(comment) || reLineDirective.MatchString(comment) || comment == generated.Directive
 }
 
 // trimNodeComment trims all non-directive comments in *nodeComment.
 // If nodeComment has an empty List after trimming, nil will be returned.
+//
+// The remaining directives are moved to the positions of the last comments of the group,
+// so a doc comment stays on the lines immediately preceding its declaration,
+// which is required by directives such as //export.
 func trimNodeComment(nodeComment *ast.CommentGroup) *ast.CommentGroup {
 	if nodeComment == nil {
 		return nil
 	}
-	nodeComment.List = slices.DeleteFunc(nodeComment.List, func(c *ast.Comment) bool { return !isDirective(c.Text) })
+	positions := make([]token.Pos, len(nodeComment.List))
+	for i, c := range nodeComment.List {
+		positions[i] = c.Slash
+	}
+	nodeComment.List = slices.DeleteFunc(nodeComment.List, func(c *ast.Comment) bool { return !isKept(c.Text) })
 	if len(nodeComment.List) == 0 {
 		return nil
 	}
+	for i, c := range nodeComment.List {
+		c.Slash = positions[len(positions)-len(nodeComment.List)+i]
+	}
 	return nodeComment
 }
 
@@ -9,7 +9,26 @@ import (
 	"testing"
 )
 
-func Test_isDirective(t *testing.T) {
+func Test_IsDirective(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"//go:noinline", true},
+		{"//export name", true},
+		{"//nolint:errcheck", true},
+		{"//line f:1", false},
+		{"//goingbad:secure", false},
+		{"// go:noinline", false},
+	}
+	for _, tt := range tests {
+		if got := IsDirective(tt.arg); got != tt.want {
+			t.Errorf("IsDirective(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func Test_isKept(t *testing.T) {
 	tests := []struct {
 		name string
 		arg  string
@@ -29,11 +48,12 @@ func Test_isDirective(t *testing.T) {
 
 		{"invalid go", "// go:generate cmd", false},
 		{"invalid line", "//line a", false},
+		{"tool directive", "//goingbad:secure", false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isDirective(tt.arg); got != tt.want {
-				t.Errorf("isDirective() = %v, want %v", got, tt.want)
+			if got := isKept(tt.arg); got != tt.want {
+				t.Errorf("isKept() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -1,3 +1,5 @@
+//goingbad:generated
+
 //export a
 package a
 
@@ -1,3 +1,5 @@
+//goingbad:generated
+
 // Package doc
 //
 //export a
@@ -0,0 +1,93 @@
+// Package compat generates the aliases of the original names of renamed exports,
+// so the importers not obfuscated along with a package keep building during a
+// transition.
+package compat
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/mkch/goingbad/internal/generated"
+)
+
+// Skipped is a renamed export without an alias.
+type Skipped struct {
+	Object types.Object
+	Reason string
+}
+
+// Source returns the source of a go file of pkg declaring the original names of
+// the renamed exported package level objects of pkg, referring to their new names.
+// The file is marked with generated.Directive.
+// The new names are looked up in newNames. The objects for which secure returns true
+// are not aliased, or the aliases would reveal their original names.
+// The result is nil if no alias is declared.
+func Source(pkg *types.Package, newNames map[types.Object]string, secure func(types.Object) bool) (src []byte, skipped []Skipped) {
+	decls, skipped := Decls(pkg, newNames, secure)
+	if decls == nil {
+		return nil, skipped
+	}
+	return append(Header(pkg), decls...), skipped
+}
+
+// Header returns the source of a go file of pkg before the declarations returned by [Decls].
+func Header(pkg *types.Package) []byte {
+	return []byte(generated.Directive + "\n\npackage " + pkg.Name() + "\n")
+}
+
+// Decls returns the declarations of the source returned by [Source], without the package clause,
+// which can be appended to a file generated by Source in a previous run.
+func Decls(pkg *types.Package, newNames map[types.Object]string, secure func(types.Object) bool) (decls []byte, skipped []Skipped) {
+	scope := pkg.Scope()
+	// Names of the package level declarations after renaming.
+	declared := make(map[string]bool)
+	for _, name := range scope.Names() {
+		if newName, renamed := newNames[scope.Lookup(name)]; renamed {
+			declared[newName] = true
+		} else {
+			declared[name] = true
+		}
+	}
+	var b strings.Builder
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		newName, renamed := newNames[obj]
+		if !renamed || !obj.Exported() || newName == name {
+			continue
+		}
+		if secure != nil && secure(obj) {
+			skipped = append(skipped, Skipped{obj, "secure"})
+			continue
+		}
+		if declared[name] {
+			skipped = append(skipped, Skipped{obj, "original name is declared after renaming"})
+			continue
+		}
+		var decl string
+		switch obj := obj.(type) {
+		case *types.Const:
+			decl = fmt.Sprintf("const %v = %v", name, newName)
+		case *types.TypeName:
+			if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				skipped = append(skipped, Skipped{obj, "generic type"})
+				continue
+			}
+			decl = fmt.Sprintf("type %v = %v", name, newName)
+		case *types.Func:
+			if obj.Signature().TypeParams().Len() > 0 {
+				skipped = append(skipped, Skipped{obj, "generic function"})
+				continue
+			}
+			decl = fmt.Sprintf("var %v = %v", name, newName)
+		default:
+			skipped = append(skipped, Skipped{obj, "variables can not be aliased"})
+			continue
+		}
+		fmt.Fprintf(&b, "\n// Deprecated: %v is the original name of %v, kept for compatibility.\n%v\n", name, newName, decl)
+	}
+	if b.Len() == 0 {
+		return nil, skipped
+	}
+	return []byte(b.String()), skipped
+}
@@ -0,0 +1,84 @@
+package compat
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+)
+
+func Test_Source(t *testing.T) {
+	const src = `package a
+
+const Limit = 10
+
+type Point struct{ X int }
+
+type List[T any] []T
+
+func Run() {}
+
+func Map[T any](T) {}
+
+var Default Point
+
+func Taken() {}
+
+func Kept() {}
+
+func Token() {}
+
+func internal() {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("example.com/a", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newNames := make(map[types.Object]string)
+	for old, new := range map[string]string{
+		"Limit": "A", "Point": "B", "List": "C", "Run": "D", "Map": "E", "Default": "F",
+		"Taken": "Kept", "Kept": "Taken", "Token": "G", "internal": "a",
+	} {
+		newNames[pkg.Scope().Lookup(old)] = new
+	}
+	secure := func(obj types.Object) bool { return obj.Name() == "Token" }
+	got, skipped := Source(pkg, newNames, secure)
+	const want = `//goingbad:generated
+
+package a
+
+// Deprecated: Limit is the original name of A, kept for compatibility.
+const Limit = A
+
+// Deprecated: Point is the original name of B, kept for compatibility.
+type Point = B
+
+// Deprecated: Run is the original name of D, kept for compatibility.
+var Run = D
+`
+	if string(got) != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, string(got))
+	}
+	var skippedNames []string
+	for _, s := range skipped {
+		skippedNames = append(skippedNames, s.Object.Name())
+		if s.Object.Name() == "Token" && s.Reason != "secure" {
+			t.Fatalf("want reason secure, got %v", s.Reason)
+		}
+	}
+	if want := []string{"Default", "Kept", "List", "Map", "Taken", "Token"}; !slices.Equal(skippedNames, want) {
+		t.Fatalf("want skipped %v, got %v", want, skippedNames)
+	}
+
+	if got, _ := Source(pkg, nil, nil); got != nil {
+		t.Fatalf("want nil, got\n%v", string(got))
+	}
+}
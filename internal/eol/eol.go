@@ -0,0 +1,55 @@
+// Package eol normalizes the line endings of text files.
+package eol
+
+import (
+	"bytes"
+	"io"
+)
+
+// bom is the UTF-8 byte order mark.
+var bom = []byte("\xEF\xBB\xBF")
+
+// textSniffLen is the number of leading bytes inspected by [IsText], the same as git.
+const textSniffLen = 8000
+
+// IsText returns whether data is text: there is no NUL byte in the leading bytes.
+func IsText(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), textSniffLen)], 0) == -1
+}
+
+// Convert returns data without the leading byte order mark, and with line endings
+// normalized to "\r\n" if crlf is true, or "\n" otherwise.
+// A carriage return not followed by a line feed is not a line ending, and is kept.
+func Convert(data []byte, crlf bool) []byte {
+	data = bytes.TrimPrefix(data, bom)
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if crlf {
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+	return data
+}
+
+// writer converts the bytes written on Close.
+type writer struct {
+	w    io.WriteCloser
+	crlf bool
+	buf  bytes.Buffer
+}
+
+// NewWriter returns a writer converting the text written to it with [Convert],
+// and writing the result to w on Close. Close closes w, too.
+func NewWriter(w io.WriteCloser, crlf bool) io.WriteCloser {
+	return &writer{w: w, crlf: crlf}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	_, err := w.w.Write(Convert(w.buf.Bytes(), w.crlf))
+	if closeErr := w.w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package eol
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Convert(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		lf   string
+		crlf string
+	}{
+		{"empty", "", "", ""},
+		{"lf", "a\nb\n", "a\nb\n", "a\r\nb\r\n"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", "a\r\nb\r\n"},
+		{"mixed", "a\r\nb\nc", "a\nb\nc", "a\r\nb\r\nc"},
+		{"lone cr", "a\rb\r\n", "a\rb\n", "a\rb\r\n"},
+		{"bom", "\xEF\xBB\xBFpackage a\r\n", "package a\n", "package a\r\n"},
+		{"bom only at start", "a\xEF\xBB\xBF\n", "a\xEF\xBB\xBF\n", "a\xEF\xBB\xBF\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Convert([]byte(tt.data), false)); got != tt.lf {
+				t.Errorf("lf: want %q, got %q", tt.lf, got)
+			}
+			if got := string(Convert([]byte(tt.data), true)); got != tt.crlf {
+				t.Errorf("crlf: want %q, got %q", tt.crlf, got)
+			}
+		})
+	}
+}
+
+func Test_IsText(t *testing.T) {
+	if !IsText([]byte("a\r\nb")) {
+		t.Error("text is not text")
+	}
+	if IsText([]byte("PNG\x00\x01")) {
+		t.Error("binary is text")
+	}
+	if !IsText([]byte(strings.Repeat("a", textSniffLen) + "\x00")) {
+		t.Error("NUL after the leading bytes is checked")
+	}
+}
+
+type closer struct {
+	strings.Builder
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_NewWriter(t *testing.T) {
+	var c closer
+	w := NewWriter(&c, true)
+	// The CRLF is split between writes.
+	for _, s := range []string{"\xEF\xBB\xBFa\r", "\nb\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Len() != 0 {
+		t.Fatal("written before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\r\nb\r\n"; c.String() != want || !c.closed {
+		t.Fatalf("want %q closed, got %q closed=%v", want, c.String(), c.closed)
+	}
+}
@@ -0,0 +1,96 @@
+// Package filename generates names of output go files.
+package filename
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// https://pkg.go.dev/cmd/go#hdr-Build_constraints
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
+
+// Split splits the base name of a go file into stem and suffix.
+// The suffix consists of the parts of name that are meaningful to the go command:
+// the optional _GOOS, _GOARCH or _GOOS_GOARCH build constraint, the optional _test
+// and the .go extension.
+func Split(name string) (stem, suffix string) {
+	stem, _ = strings.CutSuffix(name, ".go")
+	suffix = ".go"
+	if s, ok := strings.CutSuffix(stem, "_test"); ok {
+		stem, suffix = s, "_test"+suffix
+	}
+	// The leading element is never a build constraint: "linux.go" is not linux only.
+	elems := strings.Split(stem, "_")
+	n := 0
+	if l := len(elems); l >= 3 && knownOS[elems[l-2]] && knownArch[elems[l-1]] {
+		n = 2
+	} else if l >= 2 && (knownOS[elems[l-1]] || knownArch[elems[l-1]]) {
+		n = 1
+	}
+	if n > 0 {
+		i := len(stem) - len(strings.Join(elems[len(elems)-n:], "_")) - 1
+		stem, suffix = stem[:i], stem[i:]+suffix
+	}
+	return
+}
+
+// IsPlatform returns whether the go file name has a _GOOS, _GOARCH or _GOOS_GOARCH
+// build constraint, such as "a_windows.go".
+func IsPlatform(name string) bool {
+	_, suffix := Split(name)
+	return suffix != ".go" && suffix != "_test.go"
+}
+
+// Data is the data used to execute file name templates.
+type Data struct {
+	Index   int    // Index of the file in its package, starting from 0.
+	Stem    string // Original file name without suffix. See [Split].
+	Package string // Name of the package.
+	Hash8   string // First 8 hex digits of the SHA-256 of the package path and original file name.
+}
+
+// NewData returns the Data of the index-th file named name of package pkgPath.
+func NewData(index int, name, pkgName, pkgPath string) *Data {
+	stem, _ := Split(name)
+	sum := sha256.Sum256([]byte(pkgPath + "/" + name))
+	return &Data{Index: index, Stem: stem, Package: pkgName, Hash8: hex.EncodeToString(sum[:4])}
+}
+
+// Execute executes tmpl to generate the new name of file name.
+// The suffix of name(see [Split]) is preserved, so the build constraints
+// implied by the file name remain the same.
+func Execute(tmpl *template.Template, name string, data *Data) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	stem, _ := strings.CutSuffix(buf.String(), ".go")
+	if stem == "" || strings.ContainsAny(stem, `/\`) || path.Clean(stem) != stem ||
+		stem[0] == '_' || stem[0] == '.' {
+		// The go command ignores files starting with "_" or ".".
+		return "", fmt.Errorf("invalid file name %q generated by template %q", buf.String(), tmpl.Name())
+	}
+	if newStem, _ := Split(stem + ".go"); newStem != stem {
+		return "", fmt.Errorf("file name %q generated by template %q ends with a suffix meaningful to the go command", buf.String(), tmpl.Name())
+	}
+	_, suffix := Split(name)
+	return stem + suffix, nil
+}
@@ -0,0 +1,78 @@
+package filename
+
+import (
+	"testing"
+	"text/template"
+)
+
+func Test_Split(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantStem   string
+		wantSuffix string
+	}{
+		{"a.go", "a", ".go"},
+		{"a_test.go", "a", "_test.go"},
+		{"linux.go", "linux", ".go"},
+		{"a_linux.go", "a", "_linux.go"},
+		{"a_amd64.go", "a", "_amd64.go"},
+		{"a_b_linux_amd64.go", "a_b", "_linux_amd64.go"},
+		{"a_linux_amd64_test.go", "a", "_linux_amd64_test.go"},
+		{"linux_amd64.go", "linux", "_amd64.go"},
+		{"a_amd64_linux.go", "a_amd64", "_linux.go"},
+		{"a_unknown.go", "a_unknown", ".go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStem, gotSuffix := Split(tt.name)
+			if gotStem != tt.wantStem || gotSuffix != tt.wantSuffix {
+				t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.name, gotStem, gotSuffix, tt.wantStem, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func Test_IsPlatform(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.go":                  false,
+		"a_test.go":             false,
+		"windows.go":            false,
+		"a_windows.go":          true,
+		"a_arm64_test.go":       true,
+		"a_linux_amd64_test.go": true,
+		"a_unknown.go":          false,
+	} {
+		if got := IsPlatform(name); got != want {
+			t.Errorf("IsPlatform(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func Test_Execute(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"src{{.Index}}.go", "a_windows_test.go", "src3_windows_test.go", false},
+		{"{{.Package}}_{{.Stem}}", "a.go", "p_a.go", false},
+		{"{{.Hash8}}.go", "a.go", "106b1aee.go", false},
+		{"_{{.Index}}.go", "a.go", "", true},
+		{"x/{{.Index}}.go", "a.go", "", true},
+		{"{{.Index}}_test.go", "a.go", "", true},
+		{"{{.Index}}_linux.go", "a.go", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			tmpl := template.Must(template.New(tt.tmpl).Parse(tt.tmpl))
+			got, err := Execute(tmpl, tt.name, NewData(3, tt.name, "p", "example.com/p"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
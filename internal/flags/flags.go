@@ -1,28 +1,143 @@
 package flags
 
 import (
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"iter"
 	"maps"
+	"os"
 	"path"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/postproc"
 )
 
 type Flags struct {
 	Force                 bool
+	InPlace               bool
+	Interactive           bool
+	CheckFormat           bool
 	RenameInternalExports bool
+	JSONTags              bool
+	Prune                 bool
+	BestEffort            bool
+	MaxPanics             int
+	BlankUnused           bool
 	IncludeTests          bool
+	TestFiles             TestFiles
+	Examples              Examples
+	LineEndings           LineEndings
 	OutDir                string
+	MapFile               string
+	ManifestFile          string
+	SumsFile              string
+	MapKeyFile            string
+	PreviousMap           string
+	Salt                  string
+	MessagesFile          string
+	MessagePackage        string
+	NameTable             string
+	NameTableKeyFile      string
+	PkgFile               string
+	Exclude               patternsFlag
+	CompatAliases         patternsFlag
+	ReportFile            string
+	TraceFile             string
+	ModuleFiles           string
 	KeepNames             keepFlag
+	SecureNames           keepFlag
 	Seeds                 seedsFlag
+	FileNames             fileNamesFlag
+	Presets               presetsFlag
+	Quarantine            quarantineFlag
+	PostFile              commandsFlag
+	PostPackage           commandsFlag
 	SeedFile              string
 	Debug                 bool
+	Annotate              bool
+	StrictKeep            bool
+	Plan                  bool
+	CacheDir              string
 	Verbose               bool
+	// fingerprint is the digest of the flags affecting the output files of packages,
+	// except the flags of inputFiles.
+	fingerprint string
+	// fingerprintFiles is the paths of the files of inputFiles set, by flag names.
+	fingerprintFiles map[string]string
+}
+
+// TestFiles is the policy of writing test files when tests are not included.
+type TestFiles string
+
+const (
+	OmitTests    TestFiles = "omit"    // Test files are not written.
+	CopyTests    TestFiles = "copy"    // Test files are copied as is.
+	RewriteTests TestFiles = "rewrite" // Test files are written with the uses of renamed identifiers updated.
+)
+
+func (f *TestFiles) Set(value string) error {
+	switch policy := TestFiles(value); policy {
+	case OmitTests, CopyTests, RewriteTests:
+		*f = policy
+		return nil
+	}
+	return fmt.Errorf("invalid test files policy: %v", value)
+}
+
+func (f *TestFiles) String() string {
+	return string(*f)
+}
+
+// Examples is the policy of example packages, the packages in directories
+// named "examples" or "example" of their modules.
+type Examples string
+
+const (
+	RewriteExamples Examples = "rewrite" // Example packages are obfuscated along with the packages they use.
+	OmitExamples    Examples = "omit"    // Example packages are not written, even if matched by the patterns.
+)
+
+func (f *Examples) Set(value string) error {
+	switch policy := Examples(value); policy {
+	case RewriteExamples, OmitExamples:
+		*f = policy
+		return nil
+	}
+	return fmt.Errorf("invalid examples policy: %v", value)
+}
+
+func (f *Examples) String() string {
+	return string(*f)
+}
+
+// LineEndings is the line endings of the output text files.
+type LineEndings string
+
+const (
+	PreserveLineEndings LineEndings = "preserve" // Go files are written by gofmt, and other files are copied as is.
+	LF                  LineEndings = "lf"
+	CRLF                LineEndings = "crlf"
+)
+
+func (f *LineEndings) Set(value string) error {
+	switch endings := LineEndings(value); endings {
+	case PreserveLineEndings, LF, CRLF:
+		*f = endings
+		return nil
+	}
+	return fmt.Errorf("invalid line endings: %v", value)
+}
+
+func (f *LineEndings) String() string {
+	return string(*f)
 }
 
 type seedsFlag []string
@@ -114,6 +229,25 @@ func (f *keepFlag) Contains(pkg, name string) bool {
 	return false
 }
 
+// All returns the names and their packages in sorted order.
+// The package is empty for the names kept in all packages.
+func (f *keepFlag) All() iter.Seq2[string, string] {
+	return func(yield func(pkg, name string) bool) {
+		for _, name := range slices.Sorted(maps.Keys(f.names)) {
+			if !yield("", name) {
+				return
+			}
+		}
+		for _, pkg := range slices.Sorted(maps.Keys(f.pkgs)) {
+			for _, name := range slices.Sorted(maps.Keys(f.pkgs[pkg])) {
+				if !yield(pkg, name) {
+					return
+				}
+			}
+		}
+	}
+}
+
 func (f *keepFlag) Empty() bool {
 	return len(f.names) == 0 && len(f.pkgs) == 0
 }
@@ -123,42 +257,359 @@ func (f *keepFlag) String() string {
 		return ""
 	}
 	var s []string
-	if f.names != nil {
-		s = slices.Collect(maps.Keys(f.names))
+	for pkg, name := range f.All() {
+		if pkg == "" {
+			s = append(s, name)
+		} else {
+			s = append(s, pkg+"."+name)
+		}
 	}
-	if f.pkgs != nil {
-		for pkg, names := range f.pkgs {
-			for name := range names {
-				s = append(s, pkg+"."+name)
+	return strings.Join(s, ",")
+}
+
+// Presets of the rules for code generated by well-known tools.
+const (
+	// ProtobufPreset keeps the names in files generated by protoc-gen-go
+	// which the protobuf runtime finds by reflection.
+	ProtobufPreset = "protobuf"
+)
+
+// presetsFlag is the names of enabled presets.
+type presetsFlag []string
+
+func (f *presetsFlag) Set(value string) error {
+	for preset := range strings.SplitSeq(value, ",") {
+		switch preset = strings.TrimSpace(preset); preset {
+		case ProtobufPreset:
+			if !slices.Contains(*f, preset) {
+				*f = append(*f, preset)
 			}
+		default:
+			return fmt.Errorf("unknown preset: %v", preset)
 		}
 	}
-	return strings.Join(s, ",")
+	return nil
+}
+
+func (f *presetsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Contains returns whether preset is enabled.
+func (f *presetsFlag) Contains(preset string) bool {
+	return slices.Contains(*f, preset)
+}
+
+// Detectors of the code resisting obfuscation, which quarantine packages.
+const (
+	CgoQuarantine     = "cgo"     // Files processed by cgo.
+	UnsafeQuarantine  = "unsafe"  // Uses of package unsafe.
+	ReflectQuarantine = "reflect" // Uses of package reflect.
+)
+
+// quarantineFlag is the thresholds of enabled quarantine detectors.
+// A package is quarantined if a detector finds at least threshold occurrences.
+type quarantineFlag map[string]int
+
+func (f *quarantineFlag) Set(value string) error {
+	for rule := range strings.SplitSeq(value, ",") {
+		detector, threshold, hasThreshold := strings.Cut(strings.TrimSpace(rule), "=")
+		switch detector {
+		case CgoQuarantine, UnsafeQuarantine, ReflectQuarantine:
+		default:
+			return fmt.Errorf("unknown quarantine detector: %v", detector)
+		}
+		n := 1
+		if hasThreshold {
+			var err error
+			if n, err = strconv.Atoi(threshold); err != nil || n < 1 {
+				return fmt.Errorf("invalid quarantine threshold: %v", rule)
+			}
+		}
+		if *f == nil {
+			*f = make(quarantineFlag)
+		}
+		(*f)[detector] = n
+	}
+	return nil
+}
+
+func (f *quarantineFlag) String() string {
+	var rules []string
+	for _, detector := range slices.Sorted(maps.Keys(*f)) {
+		rules = append(rules, detector+"="+strconv.Itoa((*f)[detector]))
+	}
+	return strings.Join(rules, ",")
+}
+
+// Threshold returns the threshold of detector, and whether it is enabled.
+func (f quarantineFlag) Threshold(detector string) (threshold int, ok bool) {
+	threshold, ok = f[detector]
+	return
+}
+
+// CompatAliasesFile is the name of the file written for -compat-aliases.
+const CompatAliasesFile = "compat_aliases.go"
+
+// patternsFlag is a list of package patterns.
+type patternsFlag []string
+
+func (f *patternsFlag) Set(value string) error {
+	for pattern := range strings.SplitSeq(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			*f = append(*f, pattern)
+		}
+	}
+	return nil
+}
+
+func (f *patternsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// commandsFlag is the post-processing commands, in the order specified.
+type commandsFlag []*postproc.Command
+
+func (f *commandsFlag) Set(value string) error {
+	cmd, err := postproc.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid command %q: %w", value, err)
+	}
+	*f = append(*f, cmd)
+	return nil
+}
+
+func (f *commandsFlag) String() string {
+	var s []string
+	for _, cmd := range *f {
+		s = append(s, cmd.String())
+	}
+	return strings.Join(s, ";")
+}
+
+// fileNamesFlag is the templates of output go file names.
+// The format of flag value is [path/pkg=]template.
+type fileNamesFlag struct {
+	values []string
+	def    *template.Template            // template of all packages
+	pkgs   map[string]*template.Template // templates of specified packages
+}
+
+func (f *fileNamesFlag) Set(value string) error {
+	var pkg, text = "", value
+	// "=" in template actions, such as {{$i := .Index}}, does not separate package path.
+	if before, after, found := strings.Cut(value, "="); found && !strings.Contains(before, "{{") {
+		pkg, text = before, after
+	}
+	tmpl, err := template.New(text).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+	if pkg == "" {
+		f.def = tmpl
+	} else {
+		if f.pkgs == nil {
+			f.pkgs = make(map[string]*template.Template)
+		}
+		f.pkgs[pkg] = tmpl
+	}
+	f.values = append(f.values, value)
+	return nil
+}
+
+// Template returns the file name template of package pkg.
+// The result is nil if the go files of pkg should keep their names.
+func (f *fileNamesFlag) Template(pkg string) *template.Template {
+	if tmpl := f.pkgs[pkg]; tmpl != nil {
+		return tmpl
+	}
+	return f.def
+}
+
+func (f *fileNamesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
 }
 
 //go:embed usage.txt
 var usage string
 
-func Init() *Flags {
-	var flags Flags
+// Init defines the flags on the command line and parses the command line arguments.
+// Errors are reported to the output of the command line before returned,
+// and the error is [flag.ErrHelp] if help is requested.
+func Init() (*Flags, error) {
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), usage)
 		flag.PrintDefaults()
 		fmt.Fprintln(flag.CommandLine.Output(), "\nCode repository: https://github.com/mkch/goingbad")
 	}
-	flag.BoolVar(&flags.IncludeTests, "include-test", false, "Include tests code.")
-	flag.BoolVar(&flags.IncludeTests, "t", false, "Alias for -include-test.")
-	flag.BoolVar(&flags.Force, "overwrite", false, "Overwrite existing output files.")
-	flag.BoolVar(&flags.Force, "f", false, "Alias for -overwrite.")
-	flag.StringVar(&flags.OutDir, "out-dir", "", "Path to the output directory. Required.")
-	flag.StringVar(&flags.OutDir, "o", "", "Alias for -out-dir.")
-	flag.BoolVar(&flags.RenameInternalExports, "obfuscate-internal-exports", false, "Obfuscate exports names in internal packages.")
-	flag.BoolVar(&flags.RenameInternalExports, "oie", false, "Alias for -obfuscate-internal-exports.")
-	flag.Var(&flags.KeepNames, "keep", "Keep names from obfuscating. The format of name is\nName | pkg.Name | path/pkg.Name\nNames can be listed with commas or specified via repeated -keep flags.")
-	flag.Var(&flags.Seeds, "seeds", "Seeds to generate obfuscated names. The characters of flag value are used as seeds. Default value is equivalent to alphanumeric.")
-	flag.StringVar(&flags.SeedFile, "seed-file", "", "File contains space-separated seeds.")
-	flag.BoolVar(&flags.Debug, "debug", false, "Enable debug mode.")
-	flag.BoolVar(&flags.Verbose, "v", false, "Enable verbose mode.")
-	flag.Parse()
+	flags := define(flag.CommandLine)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		return nil, err
+	}
+	flags.fingerprint, flags.fingerprintFiles = fingerprint(flag.CommandLine)
+	return flags, nil
+}
+
+// Parse parses args, the command line arguments without the program name,
+// and returns the flags and the remaining arguments, which are package patterns.
+func Parse(args []string) (flags *Flags, patterns []string, err error) {
+	fs := flag.NewFlagSet("goingbad", flag.ContinueOnError)
+	flags = define(fs)
+	if err = fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	flags.fingerprint, flags.fingerprintFiles = fingerprint(fs)
+	return flags, fs.Args(), nil
+}
+
+// outputIndependent is the flags which do not affect the output files of packages.
+var outputIndependent = gg.Set[string]{
+	"out-dir": {}, "o": {}, "cache": {}, "overwrite": {}, "f": {}, "i": {}, "in-place": {}, "check-format": {},
+	"map": {}, "name-table": {}, "manifest": {}, "sha256sums": {},
+	"report": {}, "trace": {}, "strict-keep": {}, "plan": {}, "v": {}, "debug": {},
+}
+
+// inputFiles is the flags of the input files which may affect the output.
+// The contents of the files, rather than their paths, are covered by the fingerprint.
+var inputFiles = []string{"seed-file", "pkg-file", "map-key", "name-table-key", "previous-map"}
+
+// fingerprint returns the digest of the flags set on fs which may affect the output files of packages,
+// except the flags of inputFiles, and the paths of the files of inputFiles set, by flag names.
+func fingerprint(fs *flag.FlagSet) (digest string, files map[string]string) {
+	h := sha256.New()
+	files = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(inputFiles, f.Name) {
+			files[f.Name] = f.Value.String()
+		} else if !outputIndependent.Contains(f.Name) {
+			fmt.Fprintf(h, "%q=%q\n", f.Name, f.Value.String())
+		}
+	})
+	return hex.EncodeToString(h.Sum(nil)), files
+}
+
+// Fingerprint returns the digest of the flags which may affect the output files of packages,
+// and the contents of the input files of the flags.
+// Packages obfuscated with the same fingerprint, and the same inputs, have the same output.
+func (f *Flags) Fingerprint() (string, error) {
+	h := sha256.New()
+	h.Write([]byte(f.fingerprint))
+	for _, name := range inputFiles {
+		file, ok := f.fingerprintFiles[name]
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%q=%x\n", name, sha256.Sum256(content))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// define defines all the flags on fs.
+func define(fs *flag.FlagSet) *Flags {
+	var flags Flags
+	fs.BoolVar(&flags.IncludeTests, "include-test", false, "Include tests code.\nWithout this flag, test files are written as specified by -test-files.")
+	fs.BoolVar(&flags.IncludeTests, "t", false, "Alias for -include-test.")
+	flags.TestFiles = OmitTests
+	fs.Var(&flags.TestFiles, "test-files", "Policy of test files without -include-test, one of\n"+
+		"omit: test files are not written,\n"+
+		"copy: test files are copied as is, which may reference the original names,\n"+
+		"rewrite: test files are written with their own identifiers kept and the uses of obfuscated identifiers updated.")
+	flags.Examples = RewriteExamples
+	fs.Var(&flags.Examples, "examples", "Policy of example packages in directories named examples or example, one of\n"+
+		"rewrite: example packages are obfuscated along with the packages they use,\n"+
+		"omit: example packages are not written, even if matched by the patterns.")
+	flags.LineEndings = PreserveLineEndings
+	fs.Var(&flags.LineEndings, "line-endings", "Line endings of the output text files, one of\n"+
+		"preserve: go files are written by gofmt with LF, and other files are copied as is,\n"+
+		"lf, crlf: all text files are written with the line endings and without byte order marks.")
+	fs.BoolVar(&flags.Force, "overwrite", false, "Overwrite existing output files, including the files of -map, -manifest, -sha256sums, -messages and -report.")
+	fs.BoolVar(&flags.Force, "f", false, "Alias for -overwrite.")
+	fs.BoolVar(&flags.Interactive, "i", false, "Ask whether to overwrite the existing output files, or to skip the packages writing them.\n"+
+		"Without -i or -overwrite, nothing is written if any output file exists.")
+	fs.BoolVar(&flags.InPlace, "in-place", false, "Allow writing into the directories of the source files.\nSources are overwritten if -overwrite is also set.")
+	fs.BoolVar(&flags.CheckFormat, "check-format", false, "Fail if gofmt or goimports would change any output file.")
+	fs.StringVar(&flags.OutDir, "out-dir", "", "Path to the output directory. Required.")
+	fs.StringVar(&flags.OutDir, "o", "", "Alias for -out-dir.")
+	fs.StringVar(&flags.PkgFile, "pkg-file", "", "Path to a file of package patterns, one per line, in addition to the patterns in arguments.\n"+
+		"Blank lines and lines starting with # are ignored. The patterns are passed to the go command\n"+
+		"as arguments, so their total length is limited by the command line of the operating system.")
+	fs.Var(&flags.Exclude, "exclude", "Package patterns to exclude after the patterns are expanded.\n"+
+		"Patterns can be listed with commas or specified via repeated -exclude flags.")
+	fs.Var(&flags.CompatAliases, "compat-aliases", "Package patterns of the packages to write "+CompatAliasesFile+" to, which declares the original names\n"+
+		"of renamed exports as deprecated aliases, for the importers not obfuscated with the packages.\n"+
+		"Patterns can be listed with commas or specified via repeated -compat-aliases flags.")
+	fs.StringVar(&flags.MapFile, "map", "", "Path to the mapping file of original and obfuscated names to write.")
+	fs.Var(&flags.PostFile, "post-file", "Command run on each file written to the directory of a package, after the package is written.\n"+
+		"Arguments are separated by spaces and are templates, for example \"addlicense {{.File}}\".\n"+
+		"Available fields are .File, .Dir, .Package(import path) and .Name(package name).\nCan be repeated, commands are run in order.")
+	fs.Var(&flags.PostPackage, "post-package", "Command run on the output directory of each package, after -post-file commands.\n"+
+		"Arguments are templates as in -post-file, where .File is empty. Can be repeated.")
+	fs.StringVar(&flags.ManifestFile, "manifest", "", "Path to the JSON manifest of the SHA-256 hashes of the files written to the output directory.\n"+
+		"The manifest can be checked with \"goingbad verify\".")
+	fs.StringVar(&flags.SumsFile, "sha256sums", "", "Path to the SHA-256 hashes of the files written to the output directory in the format of sha256sum,\n"+
+		"which can be checked with \"sha256sum -c\" in the output directory.")
+	fs.StringVar(&flags.Salt, "salt", "", "Salt mixed into the new names of exported identifiers, such as a release or build number,\n"+
+		"so different releases get different names. The salt is recorded in the mapping file.")
+	fs.StringVar(&flags.PreviousMap, "previous-map", "", "Path to the mapping file of the previous release, recorded in the lineage of the mapping file\n"+
+		"written with -map. The file must be signed with the key of -map-key if specified.")
+	fs.StringVar(&flags.MapKeyFile, "map-key", "", "Path to the file of the key to sign the mapping file with.")
+	fs.StringVar(&flags.MessagesFile, "messages", "", "Path to the catalog of user-facing messages to write. Messages are the string literals\n"+
+		"passed to errors.New, fmt.Errorf and the functions of log and log/slog as texts, formats or messages.")
+	fs.StringVar(&flags.MessagePackage, "message-package", "", "Directory of the package to generate in the output directory, which looks up messages by their IDs.\n"+
+		"Message literals are replaced with the lookups, so they can be localized.\n"+
+		"The name of the package is the base name of the directory.")
+	fs.StringVar(&flags.NameTable, "name-table", "", "Directory of the package to generate in the output directory, which contains the table\n"+
+		"from obfuscated names to original names, so programs can translate their own identifiers.\n"+
+		"The name of the package is the base name of the directory.")
+	fs.StringVar(&flags.NameTableKeyFile, "name-table-key", "", "Path to the file of the key to encrypt the table of -name-table with.\n"+
+		"The Load function of the generated package requires the key.")
+	fs.StringVar(&flags.ReportFile, "report", "", "Path to the JSON report of obfuscation-resistant code and the time spent in each pass to write.")
+	fs.StringVar(&flags.TraceFile, "trace", "", "Path to the runtime execution trace to write, which can be viewed with go tool trace.")
+	fs.StringVar(&flags.ModuleFiles, "module-files", "LICENSE*,LICENCE*,NOTICE*,COPYING*", "Comma-separated patterns of files to copy from module root directories, in addition to go.mod and go.sum.\n"+
+		"Matching files of vendored modules are copied to directory third_party of the output module.")
+	fs.BoolVar(&flags.RenameInternalExports, "obfuscate-internal-exports", false, "Obfuscate exports names in internal packages.")
+	fs.BoolVar(&flags.RenameInternalExports, "oie", false, "Alias for -obfuscate-internal-exports.")
+	fs.BoolVar(&flags.JSONTags, "json-tags", false, "Add json tags with the original names to obfuscated exported struct fields,\nso their json keys remain the same.")
+	fs.BoolVar(&flags.BestEffort, "best-effort", false, "Obfuscate packages with errors, keeping the names that may be referenced by unresolved identifiers.\n"+
+		"Partially obfuscated packages are listed in the report. The output may not compile.")
+	fs.IntVar(&flags.MaxPanics, "max-panics", 0, "Maximum number of declarations of a package skipped due to internal errors.\n"+
+		"Packages with more are copied verbatim, and all are listed in the report. Negative means no limit.")
+	fs.BoolVar(&flags.Prune, "prune", false, "Remove unexported declarations which are not referenced,\nand warn about unreferenced exported declarations.")
+	fs.BoolVar(&flags.BlankUnused, "blank-unused", false, "Rename the unreferenced parameters and named results of functions to _.")
+	fs.Var(&flags.KeepNames, "keep", "Keep names from obfuscating. The format of name is\nName | pkg.Name | path/pkg.Name\nNames can be listed with commas or specified via repeated -keep flags.")
+	fs.BoolVar(&flags.StrictKeep, "strict-keep", false, "Fail if a name of -keep matches no declaration in the loaded packages.\nWithout this flag, such names are warned about.")
+	fs.Var(&flags.SecureNames, "secure", "Obfuscate security-critical names with long random names. The format is the same as -keep.\nDeclarations annotated with //goingbad:secure are also security-critical.")
+	fs.Var(&flags.Seeds, "seeds", "Seeds to generate obfuscated names. The characters of flag value are used as seeds. Default value is equivalent to alphanumeric.\n"+
+		"Seeds must include a lower case letter, a letter without case or _ to start unexported names, and an upper case letter\n"+
+		"to start exported names if they are obfuscated with -oie. Seeds not composed of letters and digits, such as combining marks, are not used.")
+	fs.Var(&flags.FileNames, "file-names", "Template of output go file names, in the format of [path/pkg=]template.\n"+
+		"The template is executed with the fields .Index, .Stem, .Package and .Hash8.\n"+
+		"Build constraint and _test suffixes of original names are preserved.\n"+
+		"Templates can be specified for packages via repeated -file-names flags.")
+	fs.Var(&flags.Quarantine, "quarantine", "Detectors quarantining packages, which are copied verbatim instead of obfuscated, in the format of\n"+
+		"detector[=threshold]. A package is quarantined if a detector finds at least threshold occurrences, 1 by default.\n"+
+		"The detectors are cgo: files processed by cgo, unsafe: uses of package unsafe, reflect: uses of package reflect.\n"+
+		"Detectors can be listed with commas or specified via repeated -quarantine flags. Quarantined packages are listed in the report.")
+	fs.Var(&flags.Presets, "preset", "Presets of rules for code generated by well-known tools. Available presets are\n"+
+		"protobuf: keep the internal fields and the methods of messages in files generated by protoc-gen-go.\n"+
+		"Presets can be listed with commas or specified via repeated -preset flags.")
+	fs.StringVar(&flags.SeedFile, "seed-file", "", "File contains space-separated seeds.")
+	fs.StringVar(&flags.CacheDir, "cache", "", "Path to the cache directory of incremental runs. Packages whose files, dependencies and\n"+
+		"flags are unchanged since the last run with the same cache are copied from the cache instead of obfuscated.\n"+
+		"Changes of the Go toolchain are not detected, remove the cache after upgrading Go.\n"+
+		"Can not be used with -annotate, -prune, -in-place, -messages or -message-package.")
+	fs.BoolVar(&flags.Plan, "plan", false, "Print the estimated work of each package, parsing but not type checking them,\nand exit without writing. -out-dir is not required.")
+	fs.BoolVar(&flags.Debug, "debug", false, "Enable debug mode.")
+	fs.BoolVar(&flags.Annotate, "annotate", false, "Append a comment /*g2b:N*/ to each obfuscated identifier, where N is the index of its entry in the file of -map.\n"+
+		"For verifying specific renames only. Requires -map.")
+	fs.BoolVar(&flags.Verbose, "v", false, "Enable verbose mode.")
 	return &flags
 }
@@ -1,9 +1,12 @@
 package flags
 
 import (
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func Test_seesFlag(t *testing.T) {
@@ -101,4 +104,183 @@ func Test_keepFlags_Set(t *testing.T) {
 	if !flag.Contains("pkg2", "Name1") {
 		t.Fatal("pkg2.Name1")
 	}
+
+	var all []string
+	for pkg, name := range flag.All() {
+		all = append(all, pkg+"."+name)
+	}
+	if want := []string{".Name1", ".Name2", "path/pkg1.Name1", "pkg1.Name1", "pkg1.Name2", "pkg2.Name1"}; !slices.Equal(all, want) {
+		t.Fatalf("want %v, got %v", want, all)
+	}
+}
+
+func Test_fileNamesFlag(t *testing.T) {
+	var flag fileNamesFlag
+	if flag.Template("a") != nil {
+		t.Fatal("should be nil")
+	}
+	if err := flag.Set("src{{.Index}}.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.Set("example.com/a={{$i := .Index}}a{{$i}}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.Set("{{.Index"); err == nil {
+		t.Fatal("should fail")
+	}
+	if name := flag.Template("example.com/a").Name(); name != "{{$i := .Index}}a{{$i}}" {
+		t.Fatal(name)
+	}
+	if name := flag.Template("example.com/b").Name(); name != "src{{.Index}}.go" {
+		t.Fatal(name)
+	}
+	if s := flag.String(); s != "src{{.Index}}.go,example.com/a={{$i := .Index}}a{{$i}}" {
+		t.Fatal(s)
+	}
+}
+
+func Test_presetsFlag(t *testing.T) {
+	var f presetsFlag
+	if err := f.Set("protobuf, protobuf"); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Contains(ProtobufPreset) || f.String() != "protobuf" {
+		t.Fatalf("got %v", f.String())
+	}
+	if err := f.Set("unknown"); err == nil {
+		t.Fatal("unknown preset should be an error")
+	}
+}
+
+func Test_patternsFlag(t *testing.T) {
+	var f patternsFlag
+	f.Set("a/..., ./b")
+	f.Set("c,")
+	if want := []string{"a/...", "./b", "c"}; !slices.Equal(f, want) {
+		t.Fatalf("want %v, got %v", want, f)
+	}
+}
+
+func Test_quarantineFlag(t *testing.T) {
+	var f quarantineFlag
+	if err := f.Set("cgo, unsafe=5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("reflect=10,unsafe=3"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "cgo=1,reflect=10,unsafe=3"; f.String() != want {
+		t.Fatalf("want %v, got %v", want, f.String())
+	}
+	if _, ok := f.Threshold(CgoQuarantine); !ok {
+		t.Fatal("cgo is not enabled")
+	}
+	for _, value := range []string{"unknown", "unsafe=0", "reflect=x", ""} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("%q: want error", value)
+		}
+	}
+}
+
+func Test_Fingerprint(t *testing.T) {
+	dir := t.TempDir()
+	// file writes content to a file in dir and returns its path.
+	file := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	seeds, sameSeeds, otherSeeds := file("seeds", "abc"), file("same-seeds", "abc"), file("other-seeds", "xyz")
+	key, otherKey := file("key", "k1"), file("other-key", "k2")
+	fingerprint := func(args ...string) string {
+		f, _, err := Parse(append(args, "./..."))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprint, err := f.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fingerprint
+	}
+	base := fingerprint("-o", "out", "-keep", "A,B", "-seed-file", seeds, "-name-table-key", key)
+	tests := []struct {
+		args []string
+		same bool
+	}{
+		{[]string{"-o", "other", "-keep", "A,B", "-seed-file", seeds, "-name-table-key", key, "-v", "-map", "map.json", "-cache", "cache"}, true},
+		{[]string{"-o", "out", "-keep", "B", "-keep", "A", "-seed-file", seeds, "-name-table-key", key}, true},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", sameSeeds, "-name-table-key", key}, true},
+		{[]string{"-o", "out", "-keep", "A", "-seed-file", seeds, "-name-table-key", key}, false},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", seeds, "-name-table-key", key, "-oie"}, false},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", seeds, "-name-table-key", key, "-salt", "r2"}, false},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", otherSeeds, "-name-table-key", key}, false},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", seeds, "-name-table-key", otherKey}, false},
+		{[]string{"-o", "out", "-keep", "A,B", "-seed-file", seeds}, false},
+	}
+	for _, tt := range tests {
+		if got := fingerprint(tt.args...) == base; got != tt.same {
+			t.Errorf("%v: same fingerprint = %v, want %v", tt.args, got, tt.same)
+		}
+	}
+
+	f, _, err := Parse([]string{"-seed-file", filepath.Join(dir, "missing"), "./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Fingerprint(); err == nil {
+		t.Fatal("missing input file should be an error")
+	}
+}
+
+func FuzzSeedsFlag(f *testing.F) {
+	f.Add("a bc123")
+	f.Add("中文\t\n")
+	f.Add("\xff́")
+	f.Fuzz(func(t *testing.T, value string) {
+		var flag seedsFlag
+		if err := flag.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		for _, seed := range flag {
+			if utf8.RuneCountInString(seed) != 1 || strings.TrimSpace(seed) != seed {
+				t.Fatalf("invalid seed %q", seed)
+			}
+		}
+		var again seedsFlag
+		if err := again.Set(flag.String()); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(flag, again) {
+			t.Fatalf("want %q, got %q", flag, again)
+		}
+	})
+}
+
+func FuzzKeepFlag(f *testing.F) {
+	f.Add("path/pkg1.Name1")
+	f.Add("pkg1.Name2, pkg1.Name1,Name2")
+	f.Add("a.com/path/pkg.Name,中文.名字")
+	f.Add("a//pkg.name")
+	f.Add(",")
+	f.Fuzz(func(t *testing.T, value string) {
+		var flag keepFlag
+		if err := flag.Set(value); err != nil {
+			return
+		}
+		var again keepFlag
+		if err := again.Set(flag.String()); err != nil {
+			t.Fatalf("%q: %v", flag.String(), err)
+		}
+		if got, want := again.String(), flag.String(); got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+		for pkg, name := range flag.All() {
+			if !again.Contains(pkg, name) {
+				t.Fatalf("%v.%v is not kept", pkg, name)
+			}
+		}
+	})
 }
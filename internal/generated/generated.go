@@ -0,0 +1,97 @@
+// Package generated registers the code goingbad generates into its output: the packages
+// of -name-table and -message-package, the files of -compat-aliases and the calls replacing
+// message literals. The generated files are marked with [Directive], so the runs on the
+// output, such as a second run with -in-place, neither rename nor rewrite them again.
+package generated
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Directive marks a go file generated by goingbad.
+// It precedes the package clause, separated from the package doc by a blank line.
+const Directive = "//goingbad:generated"
+
+// IsGenerated returns whether file is marked with [Directive].
+func IsGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if c.Text == Directive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// object is a package level object, identified by its package path and name,
+// so the objects of the same declaration in different packages are the same.
+type object struct {
+	pkgPath, name string
+}
+
+// fileRange is the range of positions of a file.
+type fileRange struct {
+	start, end token.Pos
+}
+
+// Registry is the generated code in loaded packages.
+type Registry struct {
+	files   []fileRange
+	objects gg.Set[object]
+}
+
+// NewRegistry returns the Registry of the generated files in pkgs.
+func NewRegistry(pkgs []*packages.Package) *Registry {
+	r := &Registry{objects: make(gg.Set[object])}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if !IsGenerated(file) {
+				continue
+			}
+			r.files = append(r.files, fileRange{file.FileStart, file.FileEnd})
+			if pkg.Types == nil {
+				continue
+			}
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				if obj := scope.Lookup(name); obj.Pos() >= file.FileStart && obj.Pos() < file.FileEnd {
+					r.objects.Add(object{pkg.PkgPath, name})
+				}
+			}
+		}
+	}
+	return r
+}
+
+// Contains returns whether pos is in a generated file.
+func (r *Registry) Contains(pos token.Pos) bool {
+	for _, f := range r.files {
+		if pos >= f.start && pos < f.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Declares returns whether obj is a package level object declared in a generated file.
+func (r *Registry) Declares(obj types.Object) bool {
+	if obj == nil || obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+		return false
+	}
+	return r.objects.Contains(object{obj.Pkg().Path(), obj.Name()})
+}
+
+// Calls returns whether call, type checked with info, calls a function declared in a generated file.
+func (r *Registry) Calls(info *types.Info, call *ast.CallExpr) bool {
+	return r.Declares(typeutil.Callee(info, call))
+}
@@ -0,0 +1,73 @@
+package generated
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_IsGenerated(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"directive", "// Code generated by goingbad. DO NOT EDIT.\n\n//goingbad:generated\n\npackage a", true},
+		{"before package doc", "//goingbad:generated\n\n// Package a is a.\npackage a", true},
+		{"header only", "// Code generated by goingbad. DO NOT EDIT.\n\npackage a", false},
+		{"other directive", "//goingbad:secure\n\npackage a", false},
+		{"after package clause", "package a\n\n//goingbad:generated\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parser.ParseFile(token.NewFileSet(), "a.go", tt.src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := IsGenerated(f); got != tt.want {
+				t.Errorf("IsGenerated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Registry(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []string{
+		"//goingbad:generated\n\npackage a\n\nfunc M(id string) string { return id }\n",
+		"package a\n\nfunc F() string { return Own(M(\"id\")) }\n\nfunc Own(s string) string { return s }\n",
+	} {
+		f, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	var conf types.Config
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := conf.Check("example.com/a", fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry([]*packages.Package{{PkgPath: "example.com/a", Types: pkg, TypesInfo: info, Syntax: files, Fset: fset}})
+
+	if !r.Contains(files[0].Decls[0].Pos()) || r.Contains(files[1].Decls[0].Pos()) {
+		t.Fatal("want only the first file generated")
+	}
+	if !r.Declares(pkg.Scope().Lookup("M")) || r.Declares(pkg.Scope().Lookup("F")) {
+		t.Fatal("want only M declared")
+	}
+	own := files[1].Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt).Results[0].(*ast.CallExpr)
+	if r.Calls(info, own) || !r.Calls(info, own.Args[0].(*ast.CallExpr)) {
+		t.Fatal("want only the call to M")
+	}
+}
@@ -0,0 +1,41 @@
+// Package hostfunc finds the functions bound to the host by directives,
+// such as the functions imported from and exported to the WebAssembly host.
+package hostfunc
+
+import (
+	"go/ast"
+	"regexp"
+	"slices"
+
+	"github.com/mkch/gg"
+)
+
+// reBinding matches the directives binding functions to the host:
+//
+//	//go:wasmimport module name
+//	//go:wasmexport name
+//	//export name
+//
+// The Go names of these functions are kept, so they remain recognizable to the
+// host side bindings written against them, and cgo, which requires the names
+// of exported functions to be the same as in //export.
+var reBinding = regexp.MustCompile(`^//(go:wasmimport|go:wasmexport|export)( |$)`)
+
+// hasBinding returns whether doc has a directive binding to the host.
+func hasBinding(doc *ast.CommentGroup) bool {
+	return doc != nil && slices.ContainsFunc(doc.List, func(c *ast.Comment) bool { return reBinding.MatchString(c.Text) })
+}
+
+// KeptFuncs returns the names of the functions in files bound to the host by directives,
+// which must keep their names.
+func KeptFuncs(files []*ast.File) gg.Set[*ast.Ident] {
+	result := make(gg.Set[*ast.Ident])
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if f, ok := decl.(*ast.FuncDecl); ok && f.Recv == nil && hasBinding(f.Doc) {
+				result.Add(f.Name)
+			}
+		}
+	}
+	return result
+}
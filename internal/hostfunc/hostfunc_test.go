@@ -0,0 +1,23 @@
+package hostfunc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mkch/iter2"
+)
+
+func Test_KeptFuncs(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "testdata/a.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Sorted(iter2.Map(maps.Keys(KeptFuncs([]*ast.File{f})), func(id *ast.Ident) string { return id.Name }))
+	if want := []string{"add", "callback", "hostLog"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,25 @@
+package a
+
+import "C"
+
+//go:wasmimport env log
+func hostLog(ptr, size uint32)
+
+// add is called by the host.
+//
+//go:wasmexport add
+func add(a, b int32) int32 { return a + b }
+
+//export callback
+func callback() {}
+
+//go:noinline
+func helper() {}
+
+// exported is not a directive.
+func exported() {}
+
+type t struct{}
+
+//go:wasmexport method
+func (t) method() {}
@@ -2,7 +2,11 @@
 package idgen
 
 import (
+	"crypto/sha256"
+	"errors"
+	"math/rand/v2"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/mkch/gg"
@@ -17,11 +21,43 @@ var reLlmot = regexp.MustCompile(`^[_\p{Ll}\p{Lm}\p{Lo}\p{Lt}]+[_\pL\p{Nd}]*$`)
 // string composed of letters an digits.
 var reLNd = regexp.MustCompile(`^[_\pL\p{Nd}]+$`)
 
+// Errors of the elements which can not form IDs, returned by [Check].
+var (
+	ErrNoExportedElement   = errors.New("no seed can start an exported identifier, an upper case letter is required")
+	ErrNoUnexportedElement = errors.New("no seed can start an unexported identifier, a lower case letter, a letter without case or _ is required")
+)
+
+// Check checks that elements can form both exported and unexported IDs,
+// without the default elements [NewGenerator] adds for the missing kind.
+// The elements not used by NewGenerator, such as the ones starting with combining
+// marks or containing punctuation, are returned in unusable.
+func Check(elements ...string) (unusable []string, err error) {
+	var lu, lmot bool
+	for _, elem := range elements {
+		if reLu.MatchString(elem) {
+			lu = true
+		} else if reLlmot.MatchString(elem) {
+			lmot = true
+		} else if !reLNd.MatchString(elem) {
+			unusable = append(unusable, elem)
+		}
+	}
+	if !lu {
+		err = errors.Join(err, ErrNoExportedElement)
+	}
+	if !lmot {
+		err = errors.Join(err, ErrNoUnexportedElement)
+	}
+	return
+}
+
 type Generator struct {
 	// sort elements
 	lu   []string
 	lmot []string
 	all  []string
+	// secret is the secret the random IDs are derived from, see [Generator.WithSecret].
+	secret string
 }
 
 // New creates a new Generator.
@@ -58,6 +94,36 @@ func NewGenerator(elements ...string) *Generator {
 	return &ret
 }
 
+// Salted returns a Generator of the same elements as g, ordered by salt,
+// so the same IDs are generated in a different order for a different salt.
+// The order is determined only by the elements and salt.
+func (g *Generator) Salted(salt string) *Generator {
+	seed := sha256.Sum256([]byte(salt))
+	r := rand.New(rand.NewChaCha8(seed))
+	shuffled := func(elems []string) []string {
+		elems = slices.Clone(elems)
+		r.Shuffle(len(elems), func(i, j int) { elems[i], elems[j] = elems[j], elems[i] })
+		return elems
+	}
+	return &Generator{lu: shuffled(g.lu), lmot: shuffled(g.lmot), all: shuffled(g.all), secret: g.secret}
+}
+
+// WithSecret returns a Generator of the same elements as g, whose random IDs are
+// derived from secret. The random IDs of a Generator are determined only by its
+// elements, its secret and the keys given to [Generator.NewRandomExported] and
+// [Generator.NewRandomUnexported], so every run generates the same IDs.
+func (g *Generator) WithSecret(secret string) *Generator {
+	result := *g
+	result.secret = secret
+	return &result
+}
+
+// random returns the random number generator of the IDs of key.
+func (g *Generator) random(key string) *rand.Rand {
+	seed := sha256.Sum256([]byte(g.secret + "\x00" + key))
+	return rand.New(rand.NewChaCha8(seed))
+}
+
 var reserved = []string{
 	// built-ins
 	"any", "bool", "byte", "comparable",
@@ -77,6 +143,7 @@ var reserved = []string{
 
 	// special cases
 	"init", // init can't be a regular function name
+	"_",    // the blank identifier declares nothing
 }
 
 func forbiddenUnexported(userDefined gg.Set[string]) gg.Set[string] {
@@ -148,3 +215,42 @@ func incIndexes(indexes *[]int, base0, base int) {
 		*indexes = append(*indexes, 0)
 	}
 }
+
+// randomHelper returns a random ID composed of n elements drawn from r,
+// the first of which is chosen from d0.
+func (g *Generator) randomHelper(r *rand.Rand, d0 []string, n int, forbidden gg.Set[string]) string {
+	for {
+		var builder strings.Builder
+		builder.WriteString(d0[r.IntN(len(d0))])
+		for range n - 1 {
+			builder.WriteString(g.all[r.IntN(len(g.all))])
+		}
+		id := builder.String()
+		if !forbidden.Contains(id) {
+			return id
+		}
+	}
+}
+
+// NewRandomUnexported returns a random unexported id generator.
+// Every generated ID is composed of n elements randomly chosen each time,
+// from a random sequence determined by the secret of g and key.
+// IDs in the forbidden list will never be generated.
+func (g *Generator) NewRandomUnexported(key string, n int, forbidden gg.Set[string]) func() string {
+	forbidden = forbiddenUnexported(forbidden)
+	r := g.random(key)
+	return func() string {
+		return g.randomHelper(r, g.lmot, n, forbidden)
+	}
+}
+
+// NewRandomExported returns a random exported id generator.
+// Every generated ID is composed of n elements randomly chosen each time,
+// from a random sequence determined by the secret of g and key.
+// IDs in the forbidden list will never be generated.
+func (g *Generator) NewRandomExported(key string, n int, forbidden gg.Set[string]) func() string {
+	r := g.random(key)
+	return func() string {
+		return g.randomHelper(r, g.lu, n, forbidden)
+	}
+}
@@ -1,7 +1,12 @@
 package idgen
 
 import (
+	"errors"
+	"go/token"
+	"slices"
+	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/mkch/gg"
 )
@@ -35,12 +40,9 @@ func Test_New_exported(t *testing.T) {
 }
 
 func Test_New_unexported(t *testing.T) {
+	// The blank identifier is never generated.
 	next := NewGenerator("A", "0").NewUnexported(nil)
 
-	if id := next(); id != "_" {
-		t.Fatal(id)
-	}
-
 	if id := next(); id != "_A" {
 		t.Fatal(id)
 	}
@@ -69,3 +71,145 @@ func Test_New_unexported(t *testing.T) {
 		t.Fatal(id)
 	}
 }
+
+func Test_Check(t *testing.T) {
+	tests := []struct {
+		elements []string
+		unusable []string
+		err      []error
+	}{
+		{[]string{"A", "b", "0"}, nil, nil},
+		{[]string{"Ab", "_", "1"}, nil, nil},
+		{[]string{"Ω", "ǅ"}, nil, nil}, // Title case letters start unexported identifiers.
+		{[]string{"a", "b"}, nil, []error{ErrNoExportedElement}},
+		{[]string{"A", "0", "\u0301a"}, []string{"\u0301a"}, []error{ErrNoUnexportedElement}},
+		{[]string{"0", "1", "-"}, []string{"-"}, []error{ErrNoExportedElement, ErrNoUnexportedElement}},
+		{nil, nil, []error{ErrNoExportedElement, ErrNoUnexportedElement}},
+	}
+	for _, tt := range tests {
+		unusable, err := Check(tt.elements...)
+		if !slices.Equal(unusable, tt.unusable) {
+			t.Errorf("%q: want unusable %q, got %q", tt.elements, tt.unusable, unusable)
+		}
+		for _, want := range tt.err {
+			if !errors.Is(err, want) {
+				t.Errorf("%q: want error %v, got %v", tt.elements, want, err)
+			}
+		}
+		if len(tt.err) == 0 && err != nil {
+			t.Errorf("%q: %v", tt.elements, err)
+		}
+	}
+}
+
+func Test_NewRandom(t *testing.T) {
+	g := NewGenerator("A", "b", "0")
+	next := g.NewRandomExported("k", 32, nil)
+	id1, id2 := next(), next()
+	if len(id1) != 32 || len(id2) != 32 || id1[0] != 'A' || id1 == id2 {
+		t.Fatal(id1, id2)
+	}
+	// The IDs are determined by the secret and the key.
+	if again := g.NewRandomExported("k", 32, nil)(); again != id1 {
+		t.Fatalf("same key generated %v and %v", id1, again)
+	}
+	if other := g.NewRandomExported("l", 32, nil)(); other == id1 {
+		t.Fatalf("different keys generated %v", id1)
+	}
+	if other := g.WithSecret("s").NewRandomExported("k", 32, nil)(); other == id1 {
+		t.Fatalf("different secrets generated %v", id1)
+	}
+	next = g.NewRandomUnexported("k", 32, nil)
+	if id := next(); len(id) != 32 || id[0] != 'b' {
+		t.Fatal(id)
+	}
+	next = NewGenerator("A", "b", "c").NewRandomUnexported("k", 1, gg.Set[string]{"b": struct{}{}})
+	for range 10 {
+		if id := next(); id != "c" {
+			t.Fatal(id)
+		}
+	}
+}
+
+func Test_Salted(t *testing.T) {
+	g := NewGenerator("A", "B", "C", "D", "E", "F", "G", "H", "x", "y")
+	ids := func(g *Generator, n int) []string {
+		next := g.NewExported(nil)
+		var ids []string
+		for range n {
+			ids = append(ids, next())
+		}
+		return ids
+	}
+	// 8 IDs of one element and 8*10 IDs of two elements.
+	const n = 88
+	release1 := ids(g.Salted("release-1"), n)
+	if again := ids(g.Salted("release-1"), n); !slices.Equal(release1, again) {
+		t.Fatalf("same salt generated different IDs:\n%v\n%v", release1, again)
+	}
+	release2 := ids(g.Salted("release-2"), n)
+	if slices.Equal(release1, release2) {
+		t.Fatalf("different salts generated the same IDs: %v", release1)
+	}
+	// The same IDs are generated in different orders.
+	unsalted := ids(g, n)
+	if !slices.Equal(slices.Sorted(slices.Values(release1)), slices.Sorted(slices.Values(unsalted))) {
+		t.Fatalf("want the IDs of\n%v\ngot\n%v", unsalted, release1)
+	}
+}
+
+// FuzzGenerator checks the IDs generated from arbitrary seeds of one character, as given by -seeds.
+// Elements of one character form a prefix-free code, so the IDs composed of them are unique.
+func FuzzGenerator(f *testing.F) {
+	f.Add("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", "")
+	f.Add("A0", "")
+	f.Add("fnuc", "salt")
+	f.Add("intg", "")
+	f.Add("ǅǈ٣Ωω_", "r2")
+	f.Add("\u0301\u0300 \t12", "")
+	f.Add("\xff\xfe", "")
+	f.Fuzz(func(t *testing.T, seeds, salt string) {
+		var elements []string
+		for _, c := range seeds {
+			if !unicode.IsSpace(c) {
+				elements = append(elements, string(c))
+			}
+		}
+		g := NewGenerator(elements...)
+		if salt != "" {
+			g = g.Salted(salt)
+		}
+		const n = 300
+		check := func(kind string, next func() string, exported, unique bool) {
+			ids := make(gg.Set[string])
+			for range n {
+				id := next()
+				if !token.IsIdentifier(id) {
+					t.Fatalf("%v ID %q is not an identifier", kind, id)
+				}
+				if token.IsExported(id) != exported {
+					t.Fatalf("%v ID %q is exported: %v", kind, id, token.IsExported(id))
+				}
+				if slices.Contains(reserved, id) {
+					t.Fatalf("%v ID %q is reserved", kind, id)
+				}
+				if unique && ids.Contains(id) {
+					t.Fatalf("%v ID %q is generated twice", kind, id)
+				}
+				ids.Add(id)
+			}
+		}
+		check("exported", g.NewExported(nil), true, true)
+		check("unexported", g.NewUnexported(nil), false, true)
+		check("random exported", g.NewRandomExported(seeds, 8, nil), true, false)
+		check("random unexported", g.NewRandomUnexported(seeds, 8, nil), false, false)
+
+		forbidden := gg.Set[string]{strings.ToUpper(seeds): struct{}{}, seeds: struct{}{}}
+		next := g.NewExported(forbidden)
+		for range n {
+			if id := next(); forbidden.Contains(id) {
+				t.Fatalf("forbidden ID %q is generated", id)
+			}
+		}
+	})
+}
@@ -0,0 +1,189 @@
+// Package invariant checks the properties of source files that
+// transformations must never change.
+package invariant
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mkch/goingbad/internal/comments"
+)
+
+// BlankImports returns the quoted paths of the blank imports of file, in source order.
+// Blank imports are imported for their side effects, such as registering drivers.
+func BlankImports(file *ast.File) (paths []string) {
+	for _, spec := range file.Imports {
+		if spec.Name != nil && spec.Name.Name == "_" {
+			paths = append(paths, spec.Path.Value)
+		}
+	}
+	return
+}
+
+// BuildConstraint returns the //go:build line of file.
+// The result is empty if file has no build constraint.
+func BuildConstraint(file *ast.File) string {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "//go:build ") {
+				return c.Text
+			}
+		}
+	}
+	return ""
+}
+
+// docs returns the doc comments of the declarations and specs in file.
+func docs(file *ast.File) map[ast.Node]*ast.CommentGroup {
+	result := make(map[ast.Node]*ast.CommentGroup)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			result[decl] = decl.Doc
+		case *ast.GenDecl:
+			result[decl] = decl.Doc
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					result[spec] = spec.Doc
+				case *ast.ValueSpec:
+					result[spec] = spec.Doc
+				}
+			}
+		}
+	}
+	return result
+}
+
+// directives returns the directives in doc, in source order.
+func directives(doc *ast.CommentGroup) (result []string) {
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		if comments.IsDirective(c.Text) {
+			result = append(result, c.Text)
+		}
+	}
+	return
+}
+
+// Directives returns the directives attached to the declarations and specs of file
+// as doc comments. Declarations without directives are not in the result.
+func Directives(file *ast.File) map[ast.Node][]string {
+	result := make(map[ast.Node][]string)
+	for node, doc := range docs(file) {
+		if d := directives(doc); len(d) > 0 {
+			result[node] = d
+		}
+	}
+	return result
+}
+
+// fileInvariant is the invariant properties of a file.
+type fileInvariant struct {
+	blankImports    []string
+	buildConstraint string
+	directives      map[ast.Node][]string
+}
+
+func newFileInvariant(file *ast.File) fileInvariant {
+	return fileInvariant{BlankImports(file), BuildConstraint(file), Directives(file)}
+}
+
+// Snapshot is the invariant properties of files taken before transformations.
+type Snapshot map[*ast.File]fileInvariant
+
+// Take takes a snapshot of files.
+func (s Snapshot) Take(files ...*ast.File) {
+	for _, file := range files {
+		s[file] = newFileInvariant(file)
+	}
+}
+
+// Check checks that file still has the invariant properties recorded in s, and
+// so does src, the formatted output of file, if not nil.
+// Files not in s are not checked.
+func (s Snapshot) Check(fset *token.FileSet, file *ast.File, src []byte) error {
+	want, ok := s[file]
+	if !ok {
+		return nil
+	}
+	filename := fset.Position(file.Package).Filename
+	if err := checkFile(filename, newFileInvariant(file), want); err != nil {
+		return err
+	}
+	if err := checkDirectives(fset, file, want.directives); err != nil {
+		return err
+	}
+	if src == nil {
+		return nil
+	}
+	// The output is checked too, for the comments moved by printing.
+	outFset := token.NewFileSet()
+	out, err := parser.ParseFile(outFset, filename, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	got := newFileInvariant(out)
+	if err = checkFile(filename+" (output)", got, want); err != nil {
+		return err
+	}
+	// The declarations of the output are those of file, where the directives are checked.
+	if gotDirectives, wantDirectives := sortedDirectives(got.directives), sortedDirectives(Directives(file)); !slices.EqualFunc(gotDirectives, wantDirectives, slices.Equal) {
+		return fmt.Errorf("%v (output): directives changed from %v to %v", filename, wantDirectives, gotDirectives)
+	}
+	return nil
+}
+
+// checkFile checks that the blank imports and the build constraint of got, the invariant
+// of filename, are the same as want.
+func checkFile(filename string, got, want fileInvariant) error {
+	if !slices.Equal(got.blankImports, want.blankImports) {
+		return fmt.Errorf("%v: blank imports changed from %v to %v", filename, want.blankImports, got.blankImports)
+	}
+	if got.buildConstraint != want.buildConstraint {
+		return fmt.Errorf("%v: build constraint changed from %q to %q", filename, want.buildConstraint, got.buildConstraint)
+	}
+	return nil
+}
+
+// sortedDirectives returns the directives of the declarations in directives,
+// in the order of the declarations.
+func sortedDirectives(directives map[ast.Node][]string) (result [][]string) {
+	nodes := slices.SortedFunc(maps.Keys(directives), func(a, b ast.Node) int { return int(a.Pos() - b.Pos()) })
+	for _, node := range nodes {
+		result = append(result, directives[node])
+	}
+	return
+}
+
+// checkDirectives checks that the directives of the declarations of file, which are
+// not removed, are the same as want and still immediately precede the declarations.
+func checkDirectives(fset *token.FileSet, file *ast.File, want map[ast.Node][]string) error {
+	current := docs(file)
+	// Sorted for a deterministic error.
+	nodes := slices.SortedFunc(maps.Keys(want), func(a, b ast.Node) int { return int(a.Pos() - b.Pos()) })
+	for _, node := range nodes {
+		doc, ok := current[node]
+		if !ok {
+			continue // Removed.
+		}
+		position := fset.Position(node.Pos())
+		if got := directives(doc); !slices.Equal(got, want[node]) {
+			return fmt.Errorf("%v: directives changed from %v to %v", position, want[node], got)
+		}
+		if last := doc.List[len(doc.List)-1]; fset.Position(last.Slash).Line != position.Line-1 {
+			return fmt.Errorf("%v: directive %v is detached from declaration", position, last.Text)
+		}
+	}
+	return nil
+}
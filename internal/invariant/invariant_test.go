@@ -0,0 +1,132 @@
+package invariant
+
+import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mkch/goingbad/internal/comments"
+	"github.com/mkch/goingbad/internal/prune"
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_BlankImports(t *testing.T) {
+	_, f := parseFile(t)
+	if got, want := BlankImports(f), []string{`"embed"`, `"image/gif"`, `"image/png"`}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func Test_BuildConstraint(t *testing.T) {
+	_, f := parseFile(t)
+	if got, want := BuildConstraint(f), "//go:build linux || darwin"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+// Test_passes checks that blank imports and build constraints survive all the passes.
+func Test_passes(t *testing.T) {
+	fset, f := parseFile(t)
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	typesPkg, err := conf.Check("blank", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{Name: "blank", PkgPath: "blank", Fset: fset, Types: typesPkg, TypesInfo: info, Syntax: []*ast.File{f}}
+
+	snapshot := make(Snapshot)
+	snapshot.Take(f)
+
+	if removed := prune.Prune(pkg, nil); len(removed) != 1 {
+		t.Fatal(removed)
+	}
+	if len(f.Imports) != 3 {
+		t.Fatal("fmt should be removed")
+	}
+	comments.Trim(f)
+	if err := snapshot.Check(fset, f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Imports = f.Imports[1:]
+	if err := snapshot.Check(fset, f, nil); err == nil {
+		t.Fatal("removed blank import should be detected")
+	}
+}
+
+// Test_directives checks that cgo and compiler directives stay attached to their
+// declarations after comments are trimmed.
+func Test_directives(t *testing.T) {
+	fset, f := parseFileName(t, "testdata/cgo.go")
+	snapshot := make(Snapshot)
+	snapshot.Take(f)
+	if got := len(Directives(f)); got != 3 {
+		t.Fatalf("want 3 declarations with directives, got %v", got)
+	}
+
+	comments.Trim(f)
+	if err := snapshot.Check(fset, f, nil); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := format.Node(&out, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"//export Add\nfunc Add(",
+		"//export Sub\nfunc Sub(",
+		"//go:linkname counter runtime.counter\nvar counter int",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in\n%v", want, out.String())
+		}
+	}
+
+	if err := snapshot.Check(fset, f, []byte(out.String())); err != nil {
+		t.Fatal(err)
+	}
+	detached := strings.Replace(out.String(), "//export Add\n", "//export Add\n\n", 1)
+	if err := snapshot.Check(fset, f, []byte(detached)); err == nil {
+		t.Fatal("directive detached in the output should be detected")
+	}
+	removed := strings.Replace(out.String(), "//go:linkname counter runtime.counter\n", "", 1)
+	if err := snapshot.Check(fset, f, []byte(removed)); err == nil {
+		t.Fatal("directive removed in the output should be detected")
+	}
+
+	fn := f.Decls[1].(*ast.FuncDecl)
+	fn.Doc.List[0].Slash = fn.Doc.Pos() - 100
+	if err := snapshot.Check(fset, f, nil); err == nil {
+		t.Fatal("detached directive should be detected")
+	}
+	fn.Doc = nil
+	if err := snapshot.Check(fset, f, nil); err == nil {
+		t.Fatal("removed directive should be detected")
+	}
+}
+
+func parseFile(t *testing.T) (*token.FileSet, *ast.File) {
+	t.Helper()
+	return parseFileName(t, "testdata/blank.go")
+}
+
+func parseFileName(t *testing.T, filename string) (*token.FileSet, *ast.File) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fset, f
+}
@@ -0,0 +1,16 @@
+// Comment before build constraint.
+
+//go:build linux || darwin
+
+// Package blank has blank imports.
+package blank
+
+import (
+	_ "embed" // embed
+	"fmt"
+	// image decoders
+	_ "image/gif"
+	_ "image/png"
+)
+
+func unused() { fmt.Println() }
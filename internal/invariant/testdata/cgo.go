@@ -0,0 +1,27 @@
+package cgo
+
+// #include <stdlib.h>
+import "C"
+
+//export Add
+// Add adds two integers.
+// It is called from C.
+func Add(a, b C.int) C.int {
+	return a + b
+}
+
+// Sub subtracts b from a.
+//
+//export Sub
+func Sub(a, b C.int) C.int {
+	return a - b
+}
+
+// counter counts calls.
+//
+//go:linkname counter runtime.counter
+// It is not exported.
+var counter int
+
+// noDirective has only a doc comment.
+func noDirective() {}
@@ -0,0 +1,170 @@
+// Package manifest records the SHA-256 hashes of output files, so modifications
+// of the output after obfuscation can be detected.
+//
+// The manifest is a [schema.Manifest] in JSON. The same hashes can also be written
+// in the format of sha256sum, each line is the hex encoded hash, two spaces, and the
+// slash separated path of the file relative to the output directory:
+//
+//	e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  pkg/a.go
+//
+// so they can be checked with "sha256sum -c" in the output directory.
+package manifest
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/schema"
+)
+
+// Hash returns the hex encoded SHA-256 hash of file.
+func Hash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// New returns the manifest of files in directory root, sorted by paths.
+// Files must be in root, duplicated files are recorded once.
+func New(root string, files []string) (*schema.Manifest, error) {
+	var paths []string
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("file %v is not in %v", file, root)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	m := &schema.Manifest{Header: schema.NewHeader(schema.ManifestKind), Files: []schema.ManifestFile{}}
+	for _, path := range paths {
+		hash, err := Hash(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, schema.ManifestFile{Path: path, SHA256: hash})
+	}
+	return m, nil
+}
+
+// Add adds file in directory root to m, keeping m sorted by paths.
+func Add(m *schema.Manifest, root, file string) error {
+	added, err := New(root, []string{file})
+	if err != nil {
+		return err
+	}
+	f := added.Files[0]
+	i, found := slices.BinarySearchFunc(m.Files, f.Path, func(f schema.ManifestFile, path string) int { return strings.Compare(f.Path, path) })
+	if found {
+		m.Files[i] = f
+	} else {
+		m.Files = slices.Insert(m.Files, i, f)
+	}
+	return nil
+}
+
+// Write writes m to w in JSON.
+func Write(w io.Writer, m *schema.Manifest) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteSums writes the files of m to w in the format of sha256sum.
+func WriteSums(w io.Writer, m *schema.Manifest) error {
+	bw := bufio.NewWriter(w)
+	for _, file := range m.Files {
+		fmt.Fprintf(bw, "%v  %v\n", file.SHA256, file.Path)
+	}
+	return bw.Flush()
+}
+
+// Read reads the manifest written by [Write] from r and returns the hashes by paths.
+func Read(r io.Reader) (hashes map[string]string, err error) {
+	var m schema.Manifest
+	if err = json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	if err = m.Check(schema.ManifestKind); err != nil {
+		return nil, err
+	}
+	hashes = make(map[string]string)
+	for _, file := range m.Files {
+		if len(file.SHA256) != sha256.Size*2 || file.Path == "" {
+			return nil, fmt.Errorf("invalid manifest file %q", file.Path)
+		}
+		hashes[file.Path] = file.SHA256
+	}
+	return hashes, nil
+}
+
+// ProblemKind is the kind of a [Problem].
+type ProblemKind string
+
+const (
+	Modified ProblemKind = "modified" // File content differs from the manifest.
+	Missing  ProblemKind = "missing"  // File in the manifest does not exist.
+	Added    ProblemKind = "added"    // File not in the manifest.
+)
+
+// Problem is a file not matching the manifest.
+type Problem struct {
+	Kind ProblemKind
+	Path string // Slash separated path relative to the root.
+}
+
+// Verify checks the files in directory root against hashes read by [Read].
+// The files of root for which skip returns true are not reported as added.
+// Problems are sorted by paths.
+func Verify(root string, hashes map[string]string, skip func(path string) bool) (problems []Problem, err error) {
+	for path, hash := range hashes {
+		actual, err := Hash(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			problems = append(problems, Problem{Missing, path})
+		} else if actual != hash {
+			problems = append(problems, Problem{Modified, path})
+		}
+	}
+	err = filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		path := filepath.ToSlash(gg.Must(filepath.Rel(root, file)))
+		if _, ok := hashes[path]; !ok && (skip == nil || !skip(path)) {
+			problems = append(problems, Problem{Added, path})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(problems, func(a, b Problem) int { return strings.Compare(a.Path, b.Path) })
+	return
+}
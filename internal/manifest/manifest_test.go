@@ -0,0 +1,90 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_Verify(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) string {
+		file := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	files := []string{write("a.go", ""), write("pkg/b.go", "package pkg\n"), write("pkg/c.go", "package pkg\n")}
+
+	m, err := New(root, append(files, files[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sums strings.Builder
+	if err := WriteSums(&sums, m); err != nil {
+		t.Fatal(err)
+	}
+	const want = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  a.go\n" +
+		"a7b92614d2024fe2c230fc2384eb004c430483cd4ce7c7c12aeed66e69342a07  pkg/b.go\n" +
+		"a7b92614d2024fe2c230fc2384eb004c430483cd4ce7c7c12aeed66e69342a07  pkg/c.go\n"
+	if sums.String() != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, sums.String())
+	}
+	if _, err := New(filepath.Join(root, "pkg"), files); err == nil {
+		t.Fatal("file out of root should be an error")
+	}
+
+	// The sums are added to the manifest.
+	if err := Add(m, root, write("SHA256SUMS", sums.String())); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 4 || m.Files[0].Path != "SHA256SUMS" {
+		t.Fatalf("want SHA256SUMS added first, got %v", m.Files)
+	}
+
+	var manifest strings.Builder
+	if err := Write(&manifest, m); err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := Read(strings.NewReader(manifest.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := Verify(root, hashes, nil); err != nil {
+		t.Fatal(err)
+	} else if len(problems) > 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+
+	write("pkg/b.go", "package pkg // edited\n")
+	write("pkg/d.go", "package pkg\n")
+	write("manifest.json", manifest.String())
+	if err := os.Remove(files[2]); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := Verify(root, hashes, func(path string) bool { return path == "manifest.json" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Problem{{Modified, "pkg/b.go"}, {Missing, "pkg/c.go"}, {Added, "pkg/d.go"}}; !slices.Equal(problems, want) {
+		t.Fatalf("want %v, got %v", want, problems)
+	}
+}
+
+func Test_Read(t *testing.T) {
+	for _, manifest := range []string{
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  a.go\n",
+		`{"kind": "report", "version": 1}`,
+		`{"kind": "manifest", "version": 1, "files": [{"path": "a.go", "sha256": "abc"}]}`,
+	} {
+		if _, err := Read(strings.NewReader(manifest)); err == nil {
+			t.Errorf("%q should be invalid", manifest)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package mapping
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Keyer computes the keys of identifiers in a package.
+// The format of keys is described in [schema.Mapping].
+//
+// Keys are computed from the original names in types objects, so the syntax tree
+// can be renamed before keying.
+type Keyer struct {
+	pkg    *packages.Package
+	owners map[types.Object]string // fields and interface methods to the keys of their owners.
+	counts map[string]int          // number of locals with the same key.
+	name   func(obj types.Object) string
+}
+
+// NewKeyer creates a Keyer of pkg.
+func NewKeyer(pkg *packages.Package) *Keyer {
+	k := &Keyer{pkg: pkg, owners: make(map[types.Object]string), counts: make(map[string]int), name: types.Object.Name}
+	k.addOwners()
+	return k
+}
+
+// addOwners records the owner keys of the fields and methods of the types declared in the package.
+func (k *Keyer) addOwners() {
+	pkg := k.pkg
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if spec, ok := node.(*ast.TypeSpec); ok {
+				if obj := pkg.TypesInfo.Defs[spec.Name]; obj != nil {
+					k.addOwner(spec.Type, k.key(spec.Name, obj, false))
+				}
+			}
+			return true
+		})
+	}
+}
+
+// addOwner records owner as the owner key of the fields and methods of struct or interface type expr.
+func (k *Keyer) addOwner(expr ast.Expr, owner string) {
+	var fields *ast.FieldList
+	switch expr := expr.(type) {
+	case *ast.StructType:
+		fields = expr.Fields
+	case *ast.InterfaceType:
+		fields = expr.Methods
+	default:
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			obj := k.pkg.TypesInfo.Defs[name]
+			if obj == nil {
+				continue
+			}
+			k.owners[obj] = owner
+			k.addOwner(field.Type, owner+"."+k.name(obj))
+		}
+	}
+}
+
+// Key returns the key of the identifier id which defines obj.
+// Obj is nil if id is the symbolic variable of a type switch, and name is
+// the original name of id.
+//
+// Keys of local identifiers depend on the order of calls, which should
+// be in the order of their positions.
+func (k *Keyer) Key(id *ast.Ident, obj types.Object, name string) string {
+	if obj != nil {
+		name = k.name(obj)
+	}
+	return k.keyOf(id, obj, name, true)
+}
+
+func (k *Keyer) key(id *ast.Ident, obj types.Object, count bool) string {
+	return k.keyOf(id, obj, k.name(obj), count)
+}
+
+func (k *Keyer) keyOf(id *ast.Ident, obj types.Object, name string, count bool) string {
+	prefix, local := k.prefix(id.Pos(), obj)
+	if prefix == "" {
+		return name
+	}
+	key := prefix + "." + name
+	if !local || !count {
+		return key
+	}
+	k.counts[key]++
+	if n := k.counts[key]; n > 1 {
+		key += fmt.Sprintf("#%d", n)
+	}
+	return key
+}
+
+// prefix returns the key of the owner of obj defined at pos, which its key consists of
+// with the name of obj. The prefix is empty for package level objects. Local is whether
+// the prefix is the key of the enclosing declaration.
+func (k *Keyer) prefix(pos token.Pos, obj types.Object) (prefix string, local bool) {
+	if obj != nil {
+		if owner, ok := k.owners[obj]; ok {
+			return owner, false
+		}
+		if f, ok := obj.(*types.Func); ok {
+			if recv := f.Signature().Recv(); recv != nil {
+				return k.recvName(recv.Type()), false
+			}
+		}
+		if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+			return "", false
+		}
+	}
+	return k.enclosing(pos), true
+}
+
+// recvName returns the name of the named type of receiver type t.
+func (k *Keyer) recvName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		return k.name(named.Obj())
+	}
+	return t.String()
+}
+
+// enclosing returns the key of the package level declaration enclosing pos.
+func (k *Keyer) enclosing(pos token.Pos) string {
+	for _, file := range k.pkg.Syntax {
+		if pos < file.FileStart || pos >= file.FileEnd {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if pos < decl.Pos() || pos >= decl.End() {
+				continue
+			}
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if obj := k.pkg.TypesInfo.Defs[decl.Name]; obj != nil {
+					return k.key(decl.Name, obj, false)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if pos < spec.Pos() || pos >= spec.End() {
+						continue
+					}
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						if obj := k.pkg.TypesInfo.Defs[spec.Names[0]]; obj != nil {
+							return k.key(spec.Names[0], obj, false)
+						}
+					case *ast.TypeSpec:
+						if obj := k.pkg.TypesInfo.Defs[spec.Name]; obj != nil {
+							return k.key(spec.Name, obj, false)
+						}
+					}
+				}
+			}
+		}
+	}
+	// Not in any declaration, such as the package name.
+	position := k.pkg.Fset.Position(pos)
+	return fmt.Sprintf("%v:%v", filepath.Base(position.Filename), position.Line)
+}
@@ -0,0 +1,138 @@
+// Package mapping records the original and obfuscated names of identifiers.
+package mapping
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mkch/goingbad/schema"
+)
+
+// Entry is a renamed identifier.
+type Entry = schema.MappingEntry
+
+func compareEntry(a, b Entry) int {
+	if c := cmp.Compare(a.Package, b.Package); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Key, b.Key)
+}
+
+// Map is the mapping of all renamed identifiers of a run.
+// The keys of entries are computed by [Keyer].
+type Map schema.Mapping
+
+// Add adds entries to m.
+func (m *Map) Add(entries ...Entry) {
+	m.Entries = append(m.Entries, entries...)
+}
+
+// Sort sorts the entries of m by package and key.
+func (m *Map) Sort() {
+	slices.SortFunc(m.Entries, compareEntry)
+}
+
+// Save writes m to file in JSON.
+// The header is set if m is not signed.
+func (m *Map) Save(file string) error {
+	if m.Signature == "" {
+		m.Header = schema.NewHeader(schema.MappingKind)
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0666)
+}
+
+// Load reads a Map from file.
+// If key is not nil, the map must be signed with key.
+func Load(file string, key []byte) (*Map, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m Map
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if err = m.Header.Check(schema.MappingKind); err != nil {
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+	if key != nil {
+		if err = m.Verify(key); err != nil {
+			return nil, fmt.Errorf("%v: %w", file, err)
+		}
+	}
+	return &m, nil
+}
+
+// LineageAfter returns the lineage of the map of the run following the one
+// which wrote the map in file: the lineage of that map and the map itself.
+// If key is not nil, the map must be signed with key.
+func LineageAfter(file string, key []byte) ([]schema.MappingRelease, error) {
+	m, err := Load(file, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return append(slices.Clone(m.Lineage), schema.MappingRelease{Salt: m.Salt, Digest: hex.EncodeToString(sum[:])}), nil
+}
+
+// ChangeKind is the kind of a [Change].
+type ChangeKind string
+
+const (
+	Changed ChangeKind = "changed" // Obfuscated name changed.
+	Added   ChangeKind = "added"   // Identifier is renamed only in the new map.
+	Removed ChangeKind = "removed" // Identifier is renamed only in the old map.
+)
+
+// Change is a difference between two maps.
+type Change struct {
+	Kind ChangeKind
+	Old  *Entry // Nil if Kind is Added.
+	New  *Entry // Nil if Kind is Removed.
+}
+
+// Diff returns the differences from old map to new map, ordered by package and key.
+func Diff(old, new *Map) (changes []Change) {
+	oldEntries := slices.SortedFunc(slices.Values(old.Entries), compareEntry)
+	newEntries := slices.SortedFunc(slices.Values(new.Entries), compareEntry)
+	i, j := 0, 0
+	for i < len(oldEntries) || j < len(newEntries) {
+		var c int
+		switch {
+		case i == len(oldEntries):
+			c = 1
+		case j == len(newEntries):
+			c = -1
+		default:
+			c = compareEntry(oldEntries[i], newEntries[j])
+		}
+		switch {
+		case c < 0:
+			changes = append(changes, Change{Kind: Removed, Old: &oldEntries[i]})
+			i++
+		case c > 0:
+			changes = append(changes, Change{Kind: Added, New: &newEntries[j]})
+			j++
+		default:
+			if oldEntries[i].New != newEntries[j].New {
+				changes = append(changes, Change{Kind: Changed, Old: &oldEntries[i], New: &newEntries[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return
+}
@@ -0,0 +1,272 @@
+package mapping
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mkch/goingbad/schema"
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Keyer(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object), Uses: make(map[*ast.Ident]types.Object)}
+	typesPkg, err := new(types.Config).Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{Fset: fset, Types: typesPkg, TypesInfo: info, Syntax: []*ast.File{f}}
+
+	var ids []*ast.Ident
+	for id := range info.Defs {
+		if id.Name != "_" {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b *ast.Ident) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	keyer := NewKeyer(pkg)
+	var keys []string
+	for _, id := range ids {
+		keys = append(keys, keyer.Key(id, info.Defs[id], id.Name))
+	}
+	want := []string{
+		"a.go:1.a", "T", "T.F", "T.S", "T.S.G", "T.M.t", "T.M", "T.M.p", "T.M.x", "T.M.x#2",
+		"I", "I.N", "f", "f.v", "f.local", "f.local.L", "f.s",
+	}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("want %v\ngot  %v", want, keys)
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	old := &Map{Entries: []Entry{
+		{Package: "a", Key: "f", Old: "f", New: "b"},
+		{Package: "a", Key: "g", Old: "g", New: "c"},
+		{Package: "a", Key: "h", Old: "h", New: "d"},
+	}}
+	new := &Map{Entries: []Entry{
+		{Package: "a", Key: "i", Old: "i", New: "e"},
+		{Package: "a", Key: "g", Old: "g", New: "c"},
+		{Package: "a", Key: "f", Old: "f", New: "x"},
+	}}
+	var got []string
+	for _, change := range Diff(old, new) {
+		got = append(got, string(change.Kind)+" "+cmp.Or(change.Old, change.New).Key)
+	}
+	if want := []string{"changed f", "removed h", "added i"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func Test_SaveLoad(t *testing.T) {
+	m := &Map{Entries: []Entry{{Package: "a", Key: "T.f", Old: "f", New: "b"}}}
+	file := filepath.Join(t.TempDir(), "map.json")
+	if err := m.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Entries, m.Entries) {
+		t.Fatal(loaded.Entries)
+	}
+}
+
+func Test_Sign(t *testing.T) {
+	key := []byte("secret")
+	m := &Map{Entries: []Entry{{Package: "a", Key: "T.f", Old: "f", New: "b"}}}
+	if err := m.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "map.json")
+	if err := m.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file, []byte("other")); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("want %v, got %v", ErrBadSignature, err)
+	}
+
+	m.Entries[0].New = "c"
+	if err := m.Verify(key); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("tampered: want %v, got %v", ErrBadSignature, err)
+	}
+	if err := (&Map{}).Verify(key); !errors.Is(err, ErrNotSigned) {
+		t.Fatalf("unsigned: want %v, got %v", ErrNotSigned, err)
+	}
+
+	m.Entries[0].New = "b"
+	m.Salt = "release-2"
+	if err := m.Verify(key); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("salt changed: want %v, got %v", ErrBadSignature, err)
+	}
+}
+
+func Test_LineageAfter(t *testing.T) {
+	dir := t.TempDir()
+	first := &Map{Salt: "release-1", Entries: []Entry{{Package: "a", Key: "T", Old: "T", New: "A"}}}
+	firstFile := filepath.Join(dir, "1.json")
+	if err := first.Save(firstFile); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(firstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstDigest := sha256.Sum256(data)
+
+	lineage, err := LineageAfter(firstFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := &Map{Salt: "release-2", Lineage: lineage, Entries: []Entry{{Package: "a", Key: "T", Old: "T", New: "B"}}}
+	secondFile := filepath.Join(dir, "2.json")
+	if err := second.Save(secondFile); err != nil {
+		t.Fatal(err)
+	}
+	if lineage, err = LineageAfter(secondFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(lineage) != 2 || lineage[0] != (schema.MappingRelease{Salt: "release-1", Digest: hex.EncodeToString(firstDigest[:])}) ||
+		lineage[1].Salt != "release-2" {
+		t.Fatalf("unexpected lineage %v", lineage)
+	}
+	if _, err := LineageAfter(secondFile, []byte("key")); !errors.Is(err, ErrNotSigned) {
+		t.Fatalf("want %v, got %v", ErrNotSigned, err)
+	}
+}
+
+// checkFile type checks the package of the single file src.
+func checkFile(t *testing.T, path string, src any) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object), Uses: make(map[*ast.Ident]types.Object)}
+	typesPkg, err := new(types.Config).Check(path, fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: path, Name: typesPkg.Name(), Fset: fset, Types: typesPkg, TypesInfo: info, Syntax: []*ast.File{f}}
+}
+
+// sortedDefs returns the identifiers defined in pkg in the order of positions.
+func sortedDefs(pkg *packages.Package) (ids []*ast.Ident) {
+	for id := range pkg.TypesInfo.Defs {
+		if id.Name != "_" && id.Name != pkg.Name {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b *ast.Ident) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	return
+}
+
+func Test_Restorer(t *testing.T) {
+	src, err := os.ReadFile("testdata/a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := checkFile(t, "a", src)
+	// Obfuscate the definitions, and record the map entries.
+	var entries []Entry
+	var want []string
+	for _, id := range sortedDefs(pkg) {
+		want = append(want, id.Name)
+	}
+	keyer := NewKeyer(pkg)
+	renamed := make(map[types.Object]string)
+	for i, id := range sortedDefs(pkg) {
+		obj := pkg.TypesInfo.Defs[id]
+		if obj == nil {
+			continue // Symbolic variable of type switch, whose uses are implicit.
+		}
+		key := keyer.Key(id, obj, id.Name)
+		newName := fmt.Sprintf("N%d", i)
+		if id.Name == "x" {
+			newName = "N" // Shadowed locals of the same name and new name.
+		}
+		entries = append(entries, Entry{Package: "a", Key: key, Old: id.Name, New: newName})
+		renamed[obj] = newName
+		id.Name = newName
+	}
+	for id, obj := range pkg.TypesInfo.Uses {
+		if newName, ok := renamed[obj]; ok {
+			id.Name = newName
+		}
+	}
+	var buf bytes.Buffer
+	if err = format.Node(&buf, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	entries = append(entries, Entry{Package: "b", Key: "T", Old: "T", New: "N0"}) // Other packages are ignored.
+
+	obfuscated := checkFile(t, "a", buf.Bytes())
+	r := NewRestorer(obfuscated, entries)
+	var got []string
+	for _, id := range sortedDefs(obfuscated) {
+		obj := obfuscated.TypesInfo.Defs[id]
+		if obj == nil {
+			got = append(got, id.Name)
+			continue
+		}
+		name, ok := r.Name(obj)
+		if !ok {
+			t.Errorf("%v is ambiguous", id.Name)
+		}
+		got = append(got, name)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %v\ngot  %v", want, got)
+	}
+
+	// Locals of different names renamed to the same name.
+	const ambiguous = `package c
+
+func F() {
+	{
+		N := 1
+		_ = N
+	}
+	{
+		N := 2
+		_ = N
+	}
+}
+`
+	pkg = checkFile(t, "c", ambiguous)
+	r = NewRestorer(pkg, []Entry{
+		{Package: "c", Key: "F.a", Old: "a", New: "N"},
+		{Package: "c", Key: "F.b", Old: "b", New: "N"},
+	})
+	for _, id := range sortedDefs(pkg) {
+		name, ok := r.Name(pkg.TypesInfo.Defs[id])
+		if ok != (id.Name == "F") {
+			t.Errorf("%v: ambiguous = %v", id.Name, !ok)
+		}
+		if name != id.Name {
+			t.Errorf("want %v, got %v", id.Name, name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package mapping
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Restorer finds the original names of the definitions of an obfuscated package
+// in the map entries of the package.
+//
+// The keys of the definitions are computed with the original names of their owners,
+// such as the types of fields and the declarations enclosing locals, and matched
+// against the entries by their new names.
+type Restorer struct {
+	keyer *Keyer
+	// Original names of the entries, by the prefixes of their keys and their new names.
+	index map[[2]string][]string
+	names map[types.Object]string
+	// Objects whose original names are not determined by the entries.
+	ambiguous map[types.Object]bool
+}
+
+// NewRestorer returns a Restorer of pkg, an obfuscated package, with the entries of
+// the map it is obfuscated with. The entries of other packages are ignored.
+func NewRestorer(pkg *packages.Package, entries []Entry) *Restorer {
+	r := &Restorer{
+		index:     make(map[[2]string][]string),
+		names:     make(map[types.Object]string),
+		ambiguous: make(map[types.Object]bool),
+	}
+	for _, entry := range entries {
+		if entry.Package != pkg.PkgPath {
+			continue
+		}
+		key := entry.Key
+		// Locals with the same key are numbered, see Keyer.Key.
+		if i := strings.LastIndexByte(key, '#'); i >= 0 {
+			key = key[:i]
+		}
+		prefix, ok := strings.CutSuffix(key, entry.Old)
+		if !ok {
+			continue // Malformed.
+		}
+		prefix = strings.TrimSuffix(prefix, ".")
+		index := [2]string{prefix, entry.New}
+		if !slices.Contains(r.index[index], entry.Old) {
+			r.index[index] = append(r.index[index], entry.Old)
+		}
+	}
+	// Owners are keyed with their original names, which are found with the keyer.
+	r.keyer = &Keyer{pkg: pkg, owners: make(map[types.Object]string), counts: make(map[string]int), name: r.name}
+	r.keyer.addOwners()
+	return r
+}
+
+// name returns the original name of obj, or the name of obj if it is not renamed
+// or its original name is ambiguous.
+func (r *Restorer) name(obj types.Object) string {
+	if name, ok := r.names[obj]; ok {
+		return name
+	}
+	name := obj.Name()
+	prefix, _ := r.keyer.prefix(obj.Pos(), obj)
+	switch olds := r.index[[2]string{prefix, name}]; len(olds) {
+	case 0:
+	case 1:
+		name = olds[0]
+	default:
+		// Locals of the same declaration renamed to the same name in different scopes.
+		r.ambiguous[obj] = true
+	}
+	r.names[obj] = name
+	return name
+}
+
+// Name returns the original name of obj, which is defined in the package.
+// The name of obj is returned if it is not renamed. If the original name
+// can not be determined, ok is false.
+func (r *Restorer) Name(obj types.Object) (name string, ok bool) {
+	name = r.name(obj)
+	return name, !r.ambiguous[obj]
+}
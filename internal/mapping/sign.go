@@ -0,0 +1,72 @@
+package mapping
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mkch/goingbad/schema"
+)
+
+// ErrNotSigned is returned by [Map.Verify] if the map has no signature.
+var ErrNotSigned = errors.New("mapping file is not signed")
+
+// ErrBadSignature is returned by [Map.Verify] if the signature does not match.
+var ErrBadSignature = errors.New("mapping file signature mismatch")
+
+// ReadKey reads a signing key from file.
+// Leading and trailing white spaces are not part of the key.
+func ReadKey(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if key := bytes.TrimSpace(data); len(key) > 0 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%v: empty key", file)
+}
+
+// signature returns the signature of m with key, as described in [schema.Mapping].
+func (m *Map) signature(key []byte) (string, error) {
+	data, err := json.Marshal(struct {
+		schema.Header
+		Salt    string                  `json:"salt,omitempty"`
+		Lineage []schema.MappingRelease `json:"lineage,omitempty"`
+		Entries []Entry                 `json:"entries"`
+	}{m.Header, m.Salt, m.Lineage, m.Entries})
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Sign sets the header and the signature of m with key.
+// The map must not be modified after signing.
+func (m *Map) Sign(key []byte) (err error) {
+	m.Header = schema.NewHeader(schema.MappingKind)
+	m.Signature, err = m.signature(key)
+	return
+}
+
+// Verify returns nil if m is signed with key.
+func (m *Map) Verify(key []byte) error {
+	if m.Signature == "" {
+		return ErrNotSigned
+	}
+	want, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(m.Signature), []byte(want)) {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package a
+
+type T struct {
+	F int
+	S struct {
+		G int
+	}
+}
+
+func (t *T) M(p int) {
+	x := p
+	{
+		x := x
+		_ = x
+	}
+}
+
+type I interface {
+	N()
+}
+
+func f(v any) {
+	type local struct{ L int }
+	switch s := v.(type) {
+	default:
+		_ = s
+	}
+}
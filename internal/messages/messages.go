@@ -0,0 +1,217 @@
+// Package messages extracts the user-facing message literals of packages into a catalog,
+// and replaces them with lookups by their IDs.
+//
+// Message literals are found heuristically: they are the string literals passed as the
+// text of errors.New, the format of fmt.Errorf, the messages of the functions and
+// methods of package log, and the msg of the functions and methods of package log/slog.
+//
+// Formats are replaced only if they have no arguments, see [Replace].
+package messages
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/schema"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Literal is a message literal.
+type Literal struct {
+	Call   *ast.CallExpr
+	Index  int  // Index of the literal in Call.Args.
+	Format bool // Whether the literal is a format, such as the one of fmt.Errorf.
+	Text   string
+}
+
+// isMessage returns whether the argument of f at index i is a message, and whether it is a format.
+func isMessage(f *types.Func, i int) (message, format bool) {
+	if f.Pkg() == nil {
+		return false, false
+	}
+	params := f.Signature().Params()
+	if params.Len() == 0 {
+		return false, false
+	}
+	param := params.At(min(i, params.Len()-1))
+	format = param.Name() == "format"
+	switch f.Pkg().Path() {
+	case "errors":
+		return f.Name() == "New" && param.Name() == "text", false
+	case "fmt":
+		return f.Name() == "Errorf" && format, format
+	case "log":
+		// Print, Println, Fatal and Panic have only the values to print.
+		return format || param.Name() == "v" && params.Len() == 1, format
+	case "log/slog":
+		return param.Name() == "msg", false
+	}
+	return false, false
+}
+
+// Find returns the message literals of pkg, in the order of their positions.
+func Find(pkg *packages.Package) (result []Literal) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			f, _ := typeutil.Callee(pkg.TypesInfo, call).(*types.Func)
+			if f == nil {
+				return true
+			}
+			for i, arg := range call.Args {
+				lit, ok := ast.Unparen(arg).(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				message, format := isMessage(f, i)
+				if !message {
+					continue
+				}
+				if text, err := strconv.Unquote(lit.Value); err == nil {
+					result = append(result, Literal{call, i, format, text})
+				}
+			}
+			return true
+		})
+	}
+	return
+}
+
+// ID returns the ID of message text in package pkgPath, as described in [schema.Message].
+func ID(pkgPath, text string) string {
+	sum := sha256.Sum256([]byte(pkgPath + "\x00" + text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Catalog is the catalog of messages.
+type Catalog schema.Messages
+
+// Add adds the message literals lits of pkg to c.
+func (c *Catalog) Add(pkg *packages.Package, lits []Literal) {
+	index := make(map[string]int)
+	for _, lit := range lits {
+		id := ID(pkg.PkgPath, lit.Text)
+		position := pkg.Fset.Position(lit.Call.Args[lit.Index].Pos())
+		position.Filename = filepath.Base(position.Filename)
+		i, ok := index[id]
+		if !ok {
+			i = len(c.Messages)
+			index[id] = i
+			c.Messages = append(c.Messages, schema.Message{ID: id, Package: pkg.PkgPath, Text: lit.Text})
+		}
+		c.Messages[i].Positions = append(c.Messages[i].Positions, position.String())
+	}
+}
+
+// Save writes c to file in JSON.
+func (c *Catalog) Save(file string) error {
+	c.Header = schema.NewHeader(schema.MessagesKind)
+	slices.SortFunc(c.Messages, func(a, b schema.Message) int {
+		return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.ID, b.ID))
+	})
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0666)
+}
+
+//go:embed lookup.go.tmpl
+var tmplText string
+
+var tmpl = template.Must(template.New("lookup").Parse(tmplText))
+
+// Generate writes the source of package pkgName, which looks up the messages in c by their IDs.
+func Generate(w io.Writer, pkgName string, c *Catalog) error {
+	type entry struct{ ID, Text string }
+	var entries []entry
+	for _, msg := range c.Messages {
+		entries = append(entries, entry{strconv.Quote(msg.ID), strconv.Quote(msg.Text)})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.ID, b.ID) })
+	entries = slices.Compact(entries)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Package  string
+		Messages []entry
+	}{pkgName, entries}); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Replace replaces the message literals lits of pkg with the calls to function M of
+// the package generated by [Generate], which is imported from importPath as pkgName.
+// Nothing is replaced and false is returned if pkgName is already an identifier in pkg.
+//
+// Formats which are not constant fail go vet, so a format without arguments is replaced
+// with "%s" followed by the call, and the formats with arguments or % are not replaced.
+func Replace(pkg *packages.Package, lits []Literal, pkgName, importPath string) bool {
+	if len(lits) == 0 {
+		return true
+	}
+	for _, file := range pkg.Syntax {
+		used := false
+		ast.Inspect(file, func(node ast.Node) bool {
+			if id, ok := node.(*ast.Ident); ok && id.Name == pkgName {
+				used = true
+			}
+			return !used
+		})
+		if used {
+			return false
+		}
+	}
+	files := make(map[*ast.File]bool)
+	for _, lit := range lits {
+		lookup := &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent(pkgName), Sel: ast.NewIdent("M")},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(ID(pkg.PkgPath, lit.Text))}},
+		}
+		if lit.Format {
+			if lit.Index != len(lit.Call.Args)-1 || lit.Call.Ellipsis.IsValid() || strings.Contains(lit.Text, "%") {
+				continue
+			}
+			lit.Call.Args = slices.Insert(lit.Call.Args, lit.Index, ast.Expr(&ast.BasicLit{Kind: token.STRING, Value: `"%s"`}))
+			lit.Call.Args[lit.Index+1] = lookup
+		} else {
+			lit.Call.Args[lit.Index] = lookup
+		}
+		for _, file := range pkg.Syntax {
+			if lit.Call.Pos() >= file.FileStart && lit.Call.Pos() < file.FileEnd {
+				files[file] = true
+			}
+		}
+	}
+	for file := range files {
+		astutil.AddNamedImport(pkg.Fset, file, gg.If(path.Base(importPath) == pkgName, "", pkgName), importPath)
+	}
+	return true
+}
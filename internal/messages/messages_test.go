@@ -0,0 +1,109 @@
+package messages
+
+import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func loadPackage(t *testing.T) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("example.com/a", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: pkg.Path(), Fset: fset, Syntax: []*ast.File{f}, Types: pkg, TypesInfo: info}
+}
+
+func Test_Find(t *testing.T) {
+	pkg := loadPackage(t)
+	lits := Find(pkg)
+	var texts []string
+	for _, lit := range lits {
+		texts = append(texts, lit.Text)
+	}
+	want := []string{"loading %v", "started", "user logged in", "disk low", "not found", "not found", "100%% done", "failed", "open %q: %w"}
+	if !slices.Equal(texts, want) {
+		t.Fatalf("want %q\ngot  %q", want, texts)
+	}
+
+	var c Catalog
+	c.Add(pkg, lits)
+	if len(c.Messages) != 8 {
+		t.Fatalf("want 8 messages, got %v", c.Messages)
+	}
+	notFound := c.Messages[4]
+	if notFound.ID != ID("example.com/a", "not found") || !slices.Equal(notFound.Positions, []string{"a.go:17:17", "a.go:18:17"}) {
+		t.Fatalf("got %+v", notFound)
+	}
+}
+
+func Test_Replace(t *testing.T) {
+	pkg := loadPackage(t)
+	lits := Find(pkg)
+	if !Replace(pkg, lits, "msgs", "example.com/internal/msgs") {
+		t.Fatal("not replaced")
+	}
+	var out strings.Builder
+	if err := format.Node(&out, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"example.com/internal/msgs"`,
+		`errors.New(msgs.M("` + ID("example.com/a", "not found") + `"))`,
+		`slog.Info(msgs.M("` + ID("example.com/a", "user logged in") + `"), "user", "bob")`,
+		`fmt.Println("not a message")`,
+		// Formats are constant.
+		`fmt.Errorf("%s", msgs.M("` + ID("example.com/a", "failed") + `"))`,
+		`log.Printf("loading %v", "value")`,
+		`log.Printf("100%% done")`,
+		`fmt.Errorf("open %q: %w", "file", err)`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%v not found in\n%v", want, out.String())
+		}
+	}
+
+	if Replace(loadPackage(t), lits, "err", "example.com/err") {
+		t.Error("err is an identifier in the package")
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	c := &Catalog{}
+	c.Add(loadPackage(t), Find(loadPackage(t)))
+	var src strings.Builder
+	if err := Generate(&src, "msgs", c); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "msgs.go", src.String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := new(types.Config).Check("msgs", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%v", err, src.String())
+	}
+	if want := `"` + ID("example.com/a", "open %q: %w") + `": "open %q: %w",`; !strings.Contains(src.String(), want) {
+		t.Fatalf("%v not found in\n%v", want, src.String())
+	}
+}
@@ -0,0 +1,22 @@
+package a
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+func f(err error) error {
+	log.Printf("loading %v", "value")
+	log.Println("started", 42)
+	slog.Info("user logged in", "user", "bob")
+	slog.Default().Warn("disk low")
+	fmt.Println("not a message")
+	_ = fmt.Sprintf("not a message")
+	_ = errors.New("not found")
+	_ = errors.New("not found")
+	log.Printf("100%% done")
+	_ = fmt.Errorf("failed")
+	return fmt.Errorf("open %q: %w", "file", err)
+}
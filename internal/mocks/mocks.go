@@ -0,0 +1,112 @@
+// Package mocks detects generated mocks and the interface methods they depend on.
+package mocks
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// Headers of files generated by well-known mock generators:
+// mockgen, counterfeiter, mockery and moq.
+var reMockHeader = regexp.MustCompile(`^// Code generated by (MockGen|counterfeiter|mockery( v\S+)?|moq)[.;] DO NOT EDIT\.$`)
+
+// IsMock returns whether file is generated by a well-known mock generator.
+func IsMock(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if reMockHeader.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mockTypes returns the named types declared in mock files of pkg.
+func mockTypes(pkg *packages.Package) (result []*types.Named) {
+	for _, file := range pkg.Syntax {
+		if !IsMock(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if named, ok := pkg.TypesInfo.Defs[spec.Name].Type().(*types.Named); ok {
+					result = append(result, named)
+				}
+			}
+		}
+	}
+	return
+}
+
+// interfaces returns the named interfaces declared in the package scope of pkg.
+func interfaces(pkg *packages.Package) (result []*types.Interface) {
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		typeName, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+		if iface, ok := typeName.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			result = append(result, iface)
+		}
+	}
+	return
+}
+
+// KeptMethods returns the interface methods implemented by mocks in pkgs, and the
+// methods of the mocks. Renaming these methods would break the mocks, so they should be
+// kept: mocks refer to their own methods by name, such as in the calls recorded by gomock.
+// The result maps package paths to the names of methods declared in that package.
+func KeptMethods(pkgs []*packages.Package) map[string]gg.Set[string] {
+	var mocks []*types.Named
+	for _, pkg := range pkgs {
+		mocks = append(mocks, mockTypes(pkg)...)
+	}
+	if len(mocks) == 0 {
+		return nil
+	}
+
+	result := make(map[string]gg.Set[string])
+	add := func(mtd *types.Func) {
+		path := mtd.Pkg().Path()
+		if result[path] == nil {
+			result[path] = make(gg.Set[string])
+		}
+		result[path].Add(mtd.Name())
+	}
+	for _, mock := range mocks {
+		for mtd := range mock.Methods() {
+			add(mtd)
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, iface := range interfaces(pkg) {
+			for _, mock := range mocks {
+				if !types.Implements(mock, iface) && !types.Implements(types.NewPointer(mock), iface) {
+					continue
+				}
+				for mtd := range iface.Methods() {
+					add(mtd)
+				}
+				break
+			}
+		}
+	}
+	return result
+}
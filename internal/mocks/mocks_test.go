@@ -0,0 +1,77 @@
+package mocks
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"maps"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_IsMock(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"mockgen", "// Code generated by MockGen. DO NOT EDIT.\npackage a", true},
+		{"counterfeiter", "// Code generated by counterfeiter. DO NOT EDIT.\npackage a", true},
+		{"mockery", "// Code generated by mockery v2.42.0. DO NOT EDIT.\npackage a", true},
+		{"moq", "// Code generated by moq; DO NOT EDIT.\npackage a", true},
+		{"other generator", "// Code generated by stringer. DO NOT EDIT.\npackage a", false},
+		{"after package clause", "package a\n// Code generated by MockGen. DO NOT EDIT.\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parser.ParseFile(token.NewFileSet(), "a.go", tt.src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := IsMock(f); got != tt.want {
+				t.Errorf("IsMock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_KeptMethods(t *testing.T) {
+	fset := token.NewFileSet()
+	store := loadPackage(fset, "example.com/store", "testdata/store/store.go")
+	mock := loadPackage(fset, "example.com/mock", "testdata/mock/mock.go")
+
+	kept := KeptMethods([]*packages.Package{store, mock})
+	if len(kept) != 2 {
+		t.Fatal(kept)
+	}
+	got := slices.Sorted(maps.Keys(kept["example.com/store"]))
+	if want := []string{"Get", "Put"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	got = slices.Sorted(maps.Keys(kept["example.com/mock"]))
+	if want := []string{"EXPECT", "Get", "Put"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	if kept := KeptMethods([]*packages.Package{store}); kept != nil {
+		t.Fatal(kept)
+	}
+}
+
+func loadPackage(fset *token.FileSet, path, filename string) *packages.Package {
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var conf types.Config
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object), Uses: make(map[*ast.Ident]types.Object)}
+	pkg, err := conf.Check(path, fset, []*ast.File{f}, info)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &packages.Package{PkgPath: path, Types: pkg, TypesInfo: info, Syntax: []*ast.File{f}, Fset: fset}
+}
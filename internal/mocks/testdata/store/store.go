@@ -0,0 +1,12 @@
+package store
+
+type Store interface {
+	Get(key string) (string, error)
+	Put(key, value string) error
+}
+
+type Closer interface {
+	Close() error
+}
+
+type any1 interface{}
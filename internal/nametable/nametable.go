@@ -0,0 +1,100 @@
+// Package nametable generates a Go package containing the table from obfuscated
+// names to original names, so programs can translate their own identifiers.
+package nametable
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/json"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"text/template"
+
+	"github.com/mkch/goingbad/internal/mapping"
+)
+
+// Table maps import paths of packages to the obfuscated names in them, to the keys of
+// the identifiers with those names. Keys are described in [schema.Mapping].
+type Table map[string]map[string][]string
+
+// NewTable creates a Table from the entries of a map.
+// The keys of a name are in the order of entries.
+func NewTable(entries []mapping.Entry) Table {
+	table := make(Table)
+	for _, e := range entries {
+		names := table[e.Package]
+		if names == nil {
+			names = make(map[string][]string)
+			table[e.Package] = names
+		}
+		names[e.New] = append(names[e.New], e.Key)
+	}
+	return table
+}
+
+//go:embed table.go.tmpl
+var tmplText string
+
+var tmpl = template.Must(template.New("table").Parse(tmplText))
+
+// Generate writes the source of package pkgName, which contains table.
+// If key is not nil, table is encrypted with key, and the Load function of the
+// generated package requires the same key.
+func Generate(w io.Writer, pkgName string, table Table, key []byte) error {
+	data, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		data = encrypt(key, data)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, struct {
+		Package   string
+		Data      string
+		Encrypted bool
+	}{pkgName, strconv.Quote(string(data)), key != nil}); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// IsValidPackageName returns whether name can be the name of the generated package.
+func IsValidPackageName(name string) bool {
+	return token.IsIdentifier(name) && name != "_" && name != "main"
+}
+
+// encrypt encrypts data with AES-256-GCM, using the SHA-256 of key as the key.
+// The nonce is derived from data, so the result is deterministic.
+// The nonce is prepended to the result.
+func encrypt(key, data []byte) []byte {
+	k := sha256.Sum256(key)
+	gcm := newGCM(k[:])
+	mac := hmac.New(sha256.New, k[:])
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+	return gcm.Seal(nonce, nonce, data, nil)
+}
+
+func newGCM(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err) // The key is always 32 bytes.
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcm
+}
@@ -0,0 +1,81 @@
+package nametable
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkch/goingbad/internal/mapping"
+)
+
+func Test_Generate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the generated code with the go command")
+	}
+	table := NewTable([]mapping.Entry{
+		{Package: "example.com/a", Key: "T.f", Old: "f", New: "b"},
+		{Package: "example.com/a", Key: "g.x", Old: "x", New: "b"},
+		{Package: "example.com/a", Key: "h", Old: "h", New: "c"},
+	})
+	const main = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"tmp/names"
+)
+
+func main() {
+	t, err := names.Load(%v)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(t.Lookup("example.com/a", "b"), t.Lookup("example.com/a", "c"), t.Lookup("example.com/a", "d"))
+}
+`
+	tests := []struct {
+		name string
+		key  []byte
+		arg  string
+	}{
+		{"plain", nil, ""},
+		{"encrypted", []byte("secret"), `[]byte("secret")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			write := func(name, content string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var src strings.Builder
+			if err := Generate(&src, "names", table, tt.key); err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != nil && strings.Contains(src.String(), "T.f") {
+				t.Fatal("table is not encrypted")
+			}
+			write("go.mod", "module tmp\n\ngo 1.22\n")
+			write("names/names.go", src.String())
+			write("main.go", strings.Replace(main, "%v", tt.arg, 1))
+			cmd := exec.Command("go", "run", ".")
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%v\n%s\n%v", err, out, src.String())
+			}
+			if got, want := strings.TrimSpace(string(out)), "[T.f g.x] [h] []"; got != want {
+				t.Fatalf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
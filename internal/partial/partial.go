@@ -0,0 +1,35 @@
+// Package partial finds the names which can not be safely renamed in packages
+// with incomplete type information, such as the packages with missing dependencies.
+package partial
+
+import (
+	"go/ast"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// UnresolvedNames returns the names of the identifiers in pkg which are neither
+// definitions nor resolved uses, such as the selectors of values of unknown types.
+// Any declaration with one of these names may be referenced by them,
+// so it can not be renamed safely.
+func UnresolvedNames(pkg *packages.Package) gg.Set[string] {
+	result := make(gg.Set[string])
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			id, ok := node.(*ast.Ident)
+			if !ok || id.Name == "_" || id == file.Name {
+				return true
+			}
+			if _, ok := pkg.TypesInfo.Defs[id]; ok {
+				return true
+			}
+			if _, ok := pkg.TypesInfo.Uses[id]; ok {
+				return true
+			}
+			result.Add(id.Name)
+			return true
+		})
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package partial
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_UnresolvedNames(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	typesPkg, _ := conf.Check("a", fset, []*ast.File{f}, info)
+	pkg := &packages.Package{Name: "a", PkgPath: "a", Fset: fset, Types: typesPkg, TypesInfo: info, Syntax: []*ast.File{f}}
+
+	got := slices.Sorted(maps.Keys(UnresolvedNames(pkg)))
+	if want := []string{"Client", "Count", "Value", "count"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,18 @@
+package a
+
+import "example.com/missing"
+
+type T struct {
+	name  string
+	count int
+}
+
+func (t *T) String() string {
+	return t.name
+}
+
+func f(c missing.Client) int {
+	var t T
+	t.count = c.Count()
+	return c.Value().count
+}
@@ -0,0 +1,80 @@
+// Package pattern matches packages against the package patterns of the go command.
+package pattern
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/mkch/gg"
+)
+
+// IsRelative returns whether pattern is relative to current directory,
+// such as . and ./a/...
+func IsRelative(pattern string) bool {
+	return pattern == "." || pattern == ".." ||
+		strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../")
+}
+
+// Match returns whether the package of import path pkgPath in directory dir matches pattern.
+// The wildcard ... matches any string, and a trailing /... also matches the empty string,
+// as in the go command. Relative patterns are matched against absolute directory dir,
+// others against pkgPath.
+func Match(pattern, pkgPath, dir string) bool {
+	name := pkgPath
+	if IsRelative(pattern) {
+		wd, err := filepath.Abs("")
+		if err != nil {
+			return false
+		}
+		name, pattern = filepath.ToSlash(dir), filepath.ToSlash(filepath.Join(wd, pattern))
+	}
+	return compile(pattern).MatchString(name)
+}
+
+// compile compiles pattern to a regular expression, in the way of cmd/go/internal/search.
+func compile(pattern string) *regexp.Regexp {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+	// Special case: foo/... matches foo too.
+	if strings.HasSuffix(re, `/.*`) {
+		re = re[:len(re)-len(`/.*`)] + `(/.*)?`
+	}
+	return regexp.MustCompile(`^` + re + `$`)
+}
+
+// ReadFile reads the patterns in file, one per line.
+// Blank lines, the lines starting with # and the repeated patterns are ignored.
+func ReadFile(file string) (patterns []string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || slices.Contains(patterns, line) {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// MaxLen is the maximum total length of patterns. All the patterns are passed to the
+// go command as arguments, whose length is limited by the operating system, and the
+// packages can not be loaded in batches, which would not share their types.
+var MaxLen = gg.If(runtime.GOOS == "windows", 30_000, 1_000_000)
+
+// Len returns the total length of patterns as arguments of a command.
+func Len(patterns []string) (n int) {
+	for _, p := range patterns {
+		n += len(p) + 1
+	}
+	return
+}
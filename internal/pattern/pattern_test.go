@@ -0,0 +1,61 @@
+package pattern
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_Match(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern string
+		pkgPath string
+		dir     string
+		want    bool
+	}{
+		{"example.com/a", "example.com/a", "", true},
+		{"example.com/a", "example.com/ab", "", false},
+		{"example.com/a/...", "example.com/a", "", true},
+		{"example.com/a/...", "example.com/a/b/c", "", true},
+		{"example.com/a/...", "example.com/ab", "", false},
+		{"example.com/.../gen", "example.com/x/y/gen", "", true},
+		{"...gen", "example.com/x/gen", "", true},
+		{".", "example.com/x", wd, true},
+		{"./...", "example.com/x", filepath.Join(wd, "a", "b"), true},
+		{"./a/...", "example.com/x", filepath.Join(wd, "a", "b"), true},
+		{"./a", "example.com/x", filepath.Join(wd, "a", "b"), false},
+		{"../...", "example.com/x", wd, true},
+		{"./a/...", "a/b", filepath.Join(wd, "c"), false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.pkgPath, tt.dir); got != tt.want {
+			t.Errorf("Match(%q, %q, %q) = %v, want %v", tt.pattern, tt.pkgPath, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func Test_ReadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pkgs.txt")
+	const content = "# generated\nexample.com/a\n\n  ./b/...  \n#./c\nexample.com/a\n"
+	if err := os.WriteFile(file, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com/a", "./b/..."}; !slices.Equal(patterns, want) {
+		t.Fatalf("want %v, got %v", want, patterns)
+	}
+}
+
+func Test_Len(t *testing.T) {
+	if got := Len([]string{"./a/...", "example.com/b"}); got != 22 {
+		t.Fatalf("want 22, got %v", got)
+	}
+}
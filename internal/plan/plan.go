@@ -0,0 +1,158 @@
+// Package plan estimates the work of obfuscating packages from their syntax only,
+// without type checking, which is the expensive part of loading.
+package plan
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CostPerDef is the estimated time of loading, renaming and writing a definition,
+// measured on typical modules. Type checking of the dependencies is not included.
+const CostPerDef = 200 * time.Microsecond
+
+// Package is the estimated work of a package.
+type Package struct {
+	Path     string
+	Files    int // Go files.
+	Lines    int // Lines of go files.
+	Defs     int // Renamable definitions, including the exported ones.
+	Exported int // Exported package level definitions, fields and methods.
+	// Files importing reflect or the packages encoding values by reflection,
+	// whose names may depend on the original names.
+	Reflect  int
+	Unsafe   int // Files importing unsafe.
+	Cgo      int // Files importing C.
+	Asm      int // Assembly files.
+	Estimate time.Duration
+}
+
+// reflectPackages are the packages which find names by reflection.
+var reflectPackages = map[string]bool{
+	"reflect":       true,
+	"encoding/json": true,
+	"encoding/xml":  true,
+	"encoding/gob":  true,
+	"text/template": true,
+	"html/template": true,
+}
+
+// Estimate estimates the work of pkg loaded with packages.NeedSyntax and packages.NeedFiles.
+func Estimate(pkg *packages.Package) *Package {
+	p := &Package{Path: pkg.PkgPath, Files: len(pkg.Syntax)}
+	for _, f := range pkg.Syntax {
+		if file := pkg.Fset.File(f.Pos()); file != nil {
+			p.Lines += file.LineCount()
+		}
+		var reflect bool
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			switch {
+			case path == "unsafe":
+				p.Unsafe++
+			case path == "C":
+				p.Cgo++
+			case reflectPackages[path]:
+				reflect = true
+			}
+		}
+		if reflect {
+			p.Reflect++
+		}
+		countDefs(f, p)
+	}
+	for _, file := range pkg.OtherFiles {
+		if strings.EqualFold(filepath.Ext(file), ".s") {
+			p.Asm++
+		}
+	}
+	p.Estimate = time.Duration(p.Defs) * CostPerDef
+	return p
+}
+
+// countDefs counts the definitions in f, as resolved by the parser, to p.
+func countDefs(f *ast.File, p *Package) {
+	count := func(id *ast.Ident, exported bool) {
+		if id == nil || id.Name == "_" {
+			return
+		}
+		p.Defs++
+		if exported && id.IsExported() {
+			p.Exported++
+		}
+	}
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			if node.Recv != nil || node.Name.Name != "init" && (node.Name.Name != "main" || f.Name.Name != "main") {
+				count(node.Name, true)
+			}
+		case *ast.Field:
+			for _, name := range node.Names {
+				count(name, true)
+			}
+		case *ast.TypeSpec:
+			count(node.Name, f.Scope.Lookup(node.Name.Name) == node.Name.Obj)
+		case *ast.ValueSpec:
+			for _, name := range node.Names {
+				count(name, f.Scope.Lookup(name.Name) == name.Obj)
+			}
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				for _, lhs := range node.Lhs {
+					// Redeclared variables are resolved to their previous declarations.
+					if id, ok := lhs.(*ast.Ident); ok && id.Obj != nil && id.Obj.Decl == node {
+						count(id, false)
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if node.Tok == token.DEFINE {
+				for _, x := range []ast.Expr{node.Key, node.Value} {
+					if id, ok := x.(*ast.Ident); ok {
+						count(id, false)
+					}
+				}
+			}
+		case *ast.LabeledStmt:
+			count(node.Label, false)
+		}
+		return true
+	})
+}
+
+// Write writes pkgs and their total to w as a table.
+func Write(w io.Writer, pkgs []*Package) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "PACKAGE\tFILES\tLINES\tDEFS\tEXPORTED\tREFLECT\tUNSAFE\tCGO\tASM\tESTIMATE\t")
+	var total Package
+	total.Path = "total"
+	for _, p := range pkgs {
+		writePackage(tw, p)
+		total.Files += p.Files
+		total.Lines += p.Lines
+		total.Defs += p.Defs
+		total.Exported += p.Exported
+		total.Reflect += p.Reflect
+		total.Unsafe += p.Unsafe
+		total.Cgo += p.Cgo
+		total.Asm += p.Asm
+		total.Estimate += p.Estimate
+	}
+	writePackage(tw, &total)
+	return tw.Flush()
+}
+
+func writePackage(w io.Writer, p *Package) {
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", p.Path, p.Files, p.Lines, p.Defs, p.Exported,
+		p.Reflect, p.Unsafe, p.Cgo, p.Asm, p.Estimate.Round(time.Millisecond))
+}
@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Estimate(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{PkgPath: "example.com/a", Fset: fset, Syntax: []*ast.File{f},
+		OtherFiles: []string{"testdata/a_amd64.s", "testdata/a.c"}}
+	got := Estimate(pkg)
+	// Defs: Point X Y tag Size p limit Default encode p local V data err loop i.
+	want := &Package{Path: "example.com/a", Files: 1, Lines: 34, Defs: 16, Exported: 6,
+		Reflect: 1, Unsafe: 1, Asm: 1, Estimate: 16 * CostPerDef}
+	if *got != *want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	var out strings.Builder
+	if err = Write(&out, []*Package{got, {Path: "example.com/b", Files: 2, Cgo: 1, Estimate: time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want 4 lines, got\n%v", out.String())
+	}
+	if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "total 3 34 16 6 1 1 1 1 1.003s" {
+		t.Fatalf("unexpected total %q", lines[3])
+	}
+}
@@ -0,0 +1,34 @@
+package a
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+type Point struct {
+	X, Y int
+	tag  string
+}
+
+func (p *Point) Size() uintptr { return unsafe.Sizeof(*p) }
+
+const limit = 10
+
+var Default Point
+
+func init() {}
+
+func encode(p Point) ([]byte, error) {
+	type local struct{ V int }
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(local{})
+loop:
+	for i := range limit {
+		_ = i
+		break loop
+	}
+	return data, err
+}
@@ -0,0 +1,99 @@
+// Package postproc runs external commands on the output files and package directories.
+//
+// A command is a program and its arguments separated by spaces, each of which is a
+// text/template executed with [Data]. Commands are not run by a shell, for example:
+//
+//	addlicense -f LICENSE {{.File}}
+package postproc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// Data is the data used to execute the templates of command arguments.
+type Data struct {
+	File    string // Path of the output file. Empty for the commands run per package.
+	Dir     string // Output directory of the package.
+	Package string // Import path of the package.
+	Name    string // Name of the package.
+}
+
+// Command is a post-processing command.
+type Command struct {
+	text string
+	args []*template.Template
+}
+
+// Parse parses the command text.
+func Parse(text string) (*Command, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	cmd := &Command{text: text}
+	for _, field := range fields {
+		tmpl, err := template.New(field).Option("missingkey=error").Parse(field)
+		if err != nil {
+			return nil, err
+		}
+		cmd.args = append(cmd.args, tmpl)
+	}
+	return cmd, nil
+}
+
+// String returns the text of c.
+func (c *Command) String() string {
+	return c.text
+}
+
+// Args returns the program and arguments of c executed with data.
+func (c *Command) Args(data *Data) ([]string, error) {
+	args := make([]string, 0, len(c.args))
+	for _, tmpl := range c.args {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+// Error is the failure of a command.
+type Error struct {
+	Args   []string // The program and arguments run.
+	Output []byte   // Combined stdout and stderr.
+	Err    error
+}
+
+func (err *Error) Error() string {
+	msg := fmt.Sprintf("post-processor %q: %v", strings.Join(err.Args, " "), err.Err)
+	if output := bytes.TrimSpace(err.Output); len(output) > 0 {
+		msg += ": " + string(output)
+	}
+	return msg
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Run runs c with data in directory data.Dir. A failure to run is an *[Error].
+func (c *Command) Run(ctx context.Context, data *Data) error {
+	args, err := c.Args(data)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = data.Dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return &Error{Args: args, Output: output, Err: err}
+	}
+	return nil
+}
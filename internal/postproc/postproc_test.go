@@ -0,0 +1,63 @@
+package postproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_Command(t *testing.T) {
+	if _, err := Parse(" "); err == nil {
+		t.Fatal("empty command should be an error")
+	}
+	if _, err := Parse("stamp {{.File"); err == nil {
+		t.Fatal("invalid template should be an error")
+	}
+	if _, err := Parse("stamp {{base .File}}"); err == nil {
+		t.Fatal("unknown function should be an error")
+	}
+	cmd, err := Parse("stamp  -pkg={{.Package}} {{.Name}}:{{.File}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := cmd.Args(&Data{File: "dir/a.go", Dir: "dir", Package: "example.com/a", Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stamp", "-pkg=example.com/a", "a:dir/a.go"}; !slices.Equal(args, want) {
+		t.Fatalf("want %v, got %v", want, args)
+	}
+}
+
+func Test_Run(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	cmd, err := Parse("sh -c {{.Name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cmd.Run(context.Background(), &Data{Dir: dir, Name: "touch${IFS}a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Run(context.Background(), &Data{Dir: dir, Name: "echo${IFS}failed;exit${IFS}3"})
+	var cmdErr *Error
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("want *Error, got %v", err)
+	}
+	if string(cmdErr.Output) != "failed\n" {
+		t.Fatalf("unexpected output %q", cmdErr.Output)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("want exit code 3, got %v", err)
+	}
+}
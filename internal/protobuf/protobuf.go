@@ -0,0 +1,92 @@
+// Package protobuf finds the names in files generated by protoc-gen-go
+// that the protobuf runtime depends on.
+package protobuf
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+
+	"github.com/mkch/gg"
+)
+
+// Headers of files generated by protoc-gen-go and protoc-gen-go-grpc.
+var reHeader = regexp.MustCompile(`^// Code generated by protoc-gen-go(-grpc)?\. DO NOT EDIT\.$`)
+
+// IsGenerated returns whether file is generated by protoc-gen-go or protoc-gen-go-grpc.
+func IsGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if reHeader.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reflectedFields are the names of the internal fields of generated messages,
+// which the protobuf runtime finds by name with reflection.
+var reflectedFields = gg.Set[string]{
+	"state":           {},
+	"sizeCache":       {},
+	"unknownFields":   {},
+	"extensionFields": {},
+	"weakFields":      {},
+}
+
+// isReflectedField returns whether name is the name of an internal field of messages.
+// Fields prefixed with XXX_ are generated by older versions of protoc-gen-go.
+func isReflectedField(name string) bool {
+	return reflectedFields.Contains(name) || strings.HasPrefix(name, "XXX_")
+}
+
+// messageMethods are the names of the methods of generated messages,
+// which implement the interfaces of the protobuf runtime.
+var messageMethods = gg.Set[string]{
+	"ProtoReflect": {},
+	"Reset":        {},
+	"String":       {},
+	"ProtoMessage": {},
+}
+
+// isMessageMethod returns whether name is the name of a method of messages.
+// Methods prefixed with XXX_ are generated by older versions of protoc-gen-go.
+func isMessageMethod(name string) bool {
+	return messageMethods.Contains(name) || strings.HasPrefix(name, "XXX_")
+}
+
+// KeptIdents returns the identifiers of the internal fields and the methods of messages
+// in the generated files of files, which must keep their names.
+// Other identifiers in generated files, such as the message types and getters,
+// can be renamed consistently with the code using them, because the runtime
+// refers to messages and fields by the descriptors and struct tags.
+func KeptIdents(files []*ast.File) gg.Set[*ast.Ident] {
+	result := make(gg.Set[*ast.Ident])
+	for _, file := range files {
+		if !IsGenerated(file) {
+			continue
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				if node.Recv != nil && isMessageMethod(node.Name.Name) {
+					result.Add(node.Name)
+				}
+			case *ast.StructType:
+				for _, field := range node.Fields.List {
+					for _, name := range field.Names {
+						if isReflectedField(name.Name) {
+							result.Add(name)
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return result
+}
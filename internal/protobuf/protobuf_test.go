@@ -0,0 +1,33 @@
+package protobuf
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mkch/iter2"
+)
+
+func Test_KeptIdents(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"testdata/a.pb.go", "testdata/other.go"} {
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	if !IsGenerated(files[0]) || IsGenerated(files[1]) {
+		t.Fatal("IsGenerated")
+	}
+	got := slices.Sorted(iter2.Map(maps.Keys(KeptIdents(files)), func(id *ast.Ident) string { return id.Name }))
+	want := []string{"ProtoMessage", "ProtoReflect", "Reset", "String",
+		"XXX_NoUnkeyedLiteral", "XXX_Unmarshal", "XXX_sizecache", "XXX_unrecognized", "sizeCache", "state", "unknownFields"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
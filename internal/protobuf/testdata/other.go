@@ -0,0 +1,5 @@
+package a
+
+type local struct {
+	state int
+}
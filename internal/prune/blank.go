@@ -0,0 +1,59 @@
+package prune
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// BlankUnused renames the parameters and named results of the functions in pkg
+// which are never referenced to _, so they are neither renamed nor informative.
+// The types of functions do not depend on the names of parameters, so interface
+// satisfaction and method groups are not affected.
+//
+// Functions without bodies are skipped, because their parameters may be
+// referenced by name in assembly. Keep reports the identifiers not to change.
+// The objects of blanked identifiers are returned.
+func BlankUnused(pkg *packages.Package, keep func(id *ast.Ident) bool) (result []types.Object) {
+	used := make(gg.Set[types.Object])
+	for _, obj := range pkg.TypesInfo.Uses {
+		used.Add(origin(obj))
+	}
+	blank := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				obj := pkg.TypesInfo.Defs[name]
+				if name.Name == "_" || obj == nil || used.Contains(obj) || keep != nil && keep(name) {
+					continue
+				}
+				name.Name = "_"
+				result = append(result, obj)
+			}
+		}
+	}
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			var typ *ast.FuncType
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				if node.Body == nil {
+					return false
+				}
+				typ = node.Type
+			case *ast.FuncLit:
+				typ = node.Type
+			default:
+				return true
+			}
+			blank(typ.Params)
+			blank(typ.Results)
+			return true
+		})
+	}
+	return
+}
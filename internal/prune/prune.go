@@ -0,0 +1,281 @@
+// Package prune removes unreachable declarations.
+package prune
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mkch/goingbad/internal/comments"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// hasDirective returns whether doc has a directive. Declarations with directives,
+// such as //go:linkname and //export, are referenced by means other than identifiers.
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	return slices.ContainsFunc(doc.List, func(c *ast.Comment) bool { return comments.IsDirective(c.Text) })
+}
+
+// candidate is a declaration that can be removed if none of objects is referenced.
+type candidate struct {
+	file    *ast.File
+	decl    *ast.GenDecl // nil if node is a *ast.FuncDecl.
+	node    ast.Node     // *ast.FuncDecl, *ast.TypeSpec or *ast.ValueSpec.
+	objects []types.Object
+	live    bool
+}
+
+func (c *candidate) contains(pos token.Pos) bool {
+	return pos >= c.node.Pos() && pos < c.node.End()
+}
+
+// origin returns the generic object of instantiated obj.
+func origin(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.Var:
+		return obj.Origin()
+	}
+	return obj
+}
+
+// pure returns whether evaluating expr has no side effects.
+// It is conservative: any function call or conversion is considered impure.
+func pure(expr ast.Expr) (result bool) {
+	result = true
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpr:
+			result = false
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW {
+				result = false
+			}
+		case *ast.FuncLit:
+			return false // Body of function literal is not evaluated.
+		}
+		return result
+	})
+	return
+}
+
+// candidates returns all the unexported package level declarations of pkg
+// that can be safely removed if not referenced.
+func candidates(pkg *packages.Package) (result []*candidate) {
+	isMain := pkg.Name == "main"
+	defs := pkg.TypesInfo.Defs
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil || decl.Body == nil || hasDirective(decl.Doc) ||
+					ast.IsExported(name) || name == "_" || name == "init" || isMain && name == "main" {
+					continue
+				}
+				result = append(result, &candidate{file: file, node: decl, objects: []types.Object{defs[decl.Name]}})
+			case *ast.GenDecl:
+				if decl.Tok == token.IMPORT || hasDirective(decl.Doc) {
+					continue
+				}
+			specs:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if ast.IsExported(spec.Name.Name) || spec.Name.Name == "_" || hasDirective(spec.Doc) {
+							continue
+						}
+						result = append(result, &candidate{file: file, decl: decl, node: spec, objects: []types.Object{defs[spec.Name]}})
+					case *ast.ValueSpec:
+						if hasDirective(spec.Doc) {
+							continue
+						}
+						if decl.Tok == token.CONST && len(decl.Specs) > 1 {
+							// Removing a spec from a const group may change the values of iota
+							// and the implicit repetition of the specs after it.
+							continue
+						}
+						c := &candidate{file: file, decl: decl, node: spec}
+						for _, name := range spec.Names {
+							if ast.IsExported(name.Name) || name.Name == "_" {
+								continue specs
+							}
+							c.objects = append(c.objects, defs[name])
+						}
+						for _, value := range spec.Values {
+							if !pure(value) {
+								continue specs
+							}
+						}
+						result = append(result, c)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// markLive marks the candidates referenced from live code as live.
+// The candidates named by the identifiers of roots are live.
+func markLive(pkg *packages.Package, cands []*candidate, roots []*ast.File) {
+	owner := make(map[types.Object]*candidate)
+	for _, c := range cands {
+		for _, obj := range c.objects {
+			owner[obj] = c
+		}
+	}
+	// Files in roots are not type checked, so the identifiers are matched by names.
+	rootNames := make(map[string]bool)
+	for _, file := range roots {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if id, ok := node.(*ast.Ident); ok {
+				rootNames[id.Name] = true
+			}
+			return true
+		})
+	}
+	for _, c := range cands {
+		c.live = slices.ContainsFunc(c.objects, func(obj types.Object) bool { return rootNames[obj.Name()] })
+	}
+	// dead returns whether pos is in a candidate not known to be live.
+	dead := func(pos token.Pos) bool {
+		return slices.ContainsFunc(cands, func(c *candidate) bool { return !c.live && c.contains(pos) })
+	}
+	for changed := true; changed; {
+		changed = false
+		for id, obj := range pkg.TypesInfo.Uses {
+			c := owner[origin(obj)]
+			if c == nil || c.live || dead(id.Pos()) {
+				continue
+			}
+			c.live = true
+			changed = true
+		}
+	}
+}
+
+// remove removes the dead candidates from the syntax tree.
+func remove(cands []*candidate) {
+	for _, c := range cands {
+		if c.live {
+			continue
+		}
+		if c.decl != nil {
+			c.decl.Specs = slices.DeleteFunc(c.decl.Specs, func(spec ast.Spec) bool { return spec == c.node })
+		}
+	}
+	for _, c := range cands {
+		if c.live {
+			continue
+		}
+		c.file.Decls = slices.DeleteFunc(c.file.Decls, func(decl ast.Decl) bool {
+			if genDecl, ok := decl.(*ast.GenDecl); ok {
+				return genDecl == c.decl && len(genDecl.Specs) == 0
+			}
+			return decl == c.node
+		})
+	}
+}
+
+// removeUnusedImports removes the imports of file not used by code in live.
+func removeUnusedImports(pkg *packages.Package, file *ast.File, live func(pos token.Pos) bool) {
+	used := make(map[*types.Package]bool)
+	for id, obj := range pkg.TypesInfo.Uses {
+		if id.Pos() < file.FileStart || id.Pos() >= file.FileEnd || !live(id.Pos()) {
+			continue
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			used[pkgName.Imported()] = true
+		} else if obj.Pkg() != nil {
+			used[obj.Pkg()] = true // Dot imports.
+		}
+	}
+	for _, spec := range slices.Clone(file.Imports) {
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" {
+			continue // Imported for side effects.
+		}
+		pkgName, _ := pkg.TypesInfo.Defs[spec.Name].(*types.PkgName)
+		if pkgName == nil {
+			pkgName, _ = pkg.TypesInfo.Implicits[spec].(*types.PkgName)
+		}
+		if pkgName == nil || used[pkgName.Imported()] {
+			continue
+		}
+		path, _ := strconv.Unquote(spec.Path.Value)
+		astutil.DeleteNamedImport(pkg.Fset, file, name, path)
+	}
+}
+
+// Prune removes the unexported package level declarations of pkg which are
+// not referenced, and the imports no longer used after the removal.
+// The objects declared by removed declarations are returned.
+//
+// Methods, functions without body and declarations with directives are never removed,
+// neither are the variables whose initialization may have side effects.
+//
+// Roots are the files of pkg written along with pkg.Syntax but not type checked, such as
+// the files excluded from the build and the test files copied verbatim. The declarations
+// whose names are used in roots are never removed.
+func Prune(pkg *packages.Package, roots []*ast.File) (removed []types.Object) {
+	cands := candidates(pkg)
+	if len(cands) == 0 {
+		return
+	}
+	markLive(pkg, cands, roots)
+	var dead []*candidate
+	for _, c := range cands {
+		if !c.live {
+			dead = append(dead, c)
+			removed = append(removed, c.objects...)
+		}
+	}
+	if len(dead) == 0 {
+		return
+	}
+	live := func(pos token.Pos) bool {
+		return !slices.ContainsFunc(dead, func(c *candidate) bool { return c.contains(pos) })
+	}
+	remove(cands)
+	for _, file := range pkg.Syntax {
+		removeUnusedImports(pkg, file, live)
+	}
+	return
+}
+
+// UnusedExports returns the exported package level objects of pkgs
+// which are not referenced by any of pkgs.
+// Packages main and declarations in test files are not checked.
+func UnusedExports(pkgs []*packages.Package) (result []types.Object) {
+	used := make(map[types.Object]bool)
+	for _, pkg := range pkgs {
+		for _, obj := range pkg.TypesInfo.Uses {
+			used[origin(obj)] = true
+		}
+	}
+	for _, pkg := range pkgs {
+		if pkg.Name == "main" {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if obj.Exported() && !used[obj] && !strings.HasSuffix(pkg.Fset.Position(obj.Pos()).Filename, "_test.go") {
+				result = append(result, obj)
+			}
+		}
+	}
+	return
+}
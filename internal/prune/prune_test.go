@@ -0,0 +1,99 @@
+package prune
+
+import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mkch/iter2"
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Prune(t *testing.T) {
+	pkg := loadPackage("testdata/a.go")
+	removed := slices.Sorted(iter2.Map(slices.Values(Prune(pkg, nil)), types.Object.Name))
+	if want := []string{"dead1", "dead2", "deadConst", "deadType", "unused"}; !slices.Equal(removed, want) {
+		t.Fatalf("want %v, got %v", want, removed)
+	}
+
+	var got strings.Builder
+	if err := format.Node(&got, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/a-pruned.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Fatalf("want\n%v\ngot\n%v", string(want), got.String())
+	}
+}
+
+func Test_Prune_roots(t *testing.T) {
+	pkg := loadPackage("testdata/a.go")
+	// A file excluded from the build, referencing dead1 and unused.
+	root, err := parser.ParseFile(token.NewFileSet(), "a_windows.go", "package a\n\nfunc f() { _ = dead1(); _ = unused }\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := slices.Sorted(iter2.Map(slices.Values(Prune(pkg, []*ast.File{root})), types.Object.Name))
+	if want := []string{"deadConst"}; !slices.Equal(removed, want) {
+		t.Fatalf("want %v, got %v", want, removed)
+	}
+}
+
+func Test_UnusedExports(t *testing.T) {
+	pkg := loadPackage("testdata/a.go")
+	unused := slices.Collect(iter2.Map(slices.Values(UnusedExports([]*packages.Package{pkg})), types.Object.Name))
+	if want := []string{"Exported", "UnusedExport"}; !slices.Equal(unused, want) {
+		t.Fatalf("want %v, got %v", want, unused)
+	}
+}
+
+func Test_BlankUnused(t *testing.T) {
+	pkg := loadPackage("testdata/blank.go")
+	keep := func(id *ast.Ident) bool { return id.Name == "keep" }
+	blanked := slices.Collect(iter2.Map(slices.Values(BlankUnused(pkg, keep)), types.Object.Name))
+	if want := []string{"verbose", "c", "err", "n", "ok", "x", "unused"}; !slices.Equal(blanked, want) {
+		t.Fatalf("want %v, got %v", want, blanked)
+	}
+
+	var got strings.Builder
+	if err := format.Node(&got, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/blank-blanked.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Fatalf("want\n%v\ngot\n%v", string(want), got.String())
+	}
+}
+
+func loadPackage(filename string) *packages.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	pkg, err := conf.Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &packages.Package{Name: pkg.Name(), PkgPath: pkg.Path(), Fset: fset, Types: pkg, TypesInfo: info, Syntax: []*ast.File{f}}
+}
@@ -0,0 +1,34 @@
+package a
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+//go:embed a.go
+var src string
+
+var used = 1
+
+var unusedCall = fmt.Sprint(1)
+
+func Exported() int {
+	return used + live()
+}
+
+func live() int {
+	return int(liveType(0))
+}
+
+type liveType int
+
+func (t liveType) method() {}
+
+const (
+	c1 = iota
+	c2
+)
+
+func init() {}
+
+func UnusedExport() {}
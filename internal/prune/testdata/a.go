@@ -0,0 +1,51 @@
+package a
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	. "unicode"
+)
+
+//go:embed a.go
+var src string
+
+var used = 1
+
+var unused = 2
+
+var unusedCall = fmt.Sprint(1)
+
+func Exported() int {
+	return used + live()
+}
+
+func live() int {
+	return int(liveType(0))
+}
+
+type liveType int
+
+func (t liveType) method() {}
+
+type deadType struct{ s strings.Builder }
+
+func dead1() deadType {
+	return dead2()
+}
+
+func dead2() deadType {
+	_ = IsUpper('A')
+	return dead1()
+}
+
+const (
+	c1 = iota
+	c2
+)
+
+const deadConst = "dead"
+
+func init() {}
+
+func UnusedExport() {}
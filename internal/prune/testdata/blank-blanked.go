@@ -0,0 +1,31 @@
+package a
+
+import "fmt"
+
+type Stringer interface {
+	String(verbose bool) string
+}
+
+type T struct{}
+
+func (t T) String(_ bool) string { return "T" }
+
+func add(a, b, _ int) (sum int, _ error) {
+	sum = a + b
+	return
+}
+
+func named() (_ int, _ bool) {
+	return 1, true
+}
+
+func asm(x int) int
+
+func literal() {
+	f := func(_, y int) int { return y }
+	fmt.Println(f(1, 2))
+}
+
+func generic[E any](s []E, _ E) int { return len(s) }
+
+func kept(keep int) {}
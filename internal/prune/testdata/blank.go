@@ -0,0 +1,31 @@
+package a
+
+import "fmt"
+
+type Stringer interface {
+	String(verbose bool) string
+}
+
+type T struct{}
+
+func (t T) String(verbose bool) string { return "T" }
+
+func add(a, b, c int) (sum int, err error) {
+	sum = a + b
+	return
+}
+
+func named() (n int, ok bool) {
+	return 1, true
+}
+
+func asm(x int) int
+
+func literal() {
+	f := func(x, y int) int { return y }
+	fmt.Println(f(1, 2))
+}
+
+func generic[E any](s []E, unused E) int { return len(s) }
+
+func kept(keep int) {}
@@ -0,0 +1,35 @@
+// Package quarantine counts the code which resists obfuscation in packages,
+// so packages with too much of it can be copied verbatim instead of renamed.
+package quarantine
+
+import (
+	"slices"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CgoFiles returns the number of the go files of pkg processed by cgo.
+// They are not compiled themselves, but the files generated from them by cgo are.
+func CgoFiles(pkg *packages.Package) (n int) {
+	for _, f := range pkg.GoFiles {
+		if !slices.Contains(pkg.CompiledGoFiles, f) {
+			n++
+		}
+	}
+	return
+}
+
+// Uses returns the number of the uses in pkg of the objects of package path, such as "unsafe".
+// Only the uses in the go files of pkg are counted, not these in the code generated by cgo.
+func Uses(pkg *packages.Package, path string) (n int) {
+	for id, obj := range pkg.TypesInfo.Uses {
+		if obj.Pkg() == nil || obj.Pkg().Path() != path {
+			continue
+		}
+		// The files generated by cgo refer to their sources with line directives.
+		if slices.Contains(pkg.GoFiles, pkg.Fset.Position(id.Pos()).Filename) {
+			n++
+		}
+	}
+	return
+}
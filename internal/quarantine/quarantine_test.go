@@ -0,0 +1,56 @@
+package quarantine
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Uses(t *testing.T) {
+	const src = `package a
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+var size = unsafe.Sizeof(0) + unsafe.Sizeof("")
+
+var kind = reflect.TypeOf(0).Kind() == reflect.Int
+
+var p unsafe.Pointer
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	if _, err = (&types.Config{Importer: importer.Default()}).Check("a", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{Fset: fset, GoFiles: []string{"a.go"}, TypesInfo: info}
+	// Sizeof, Sizeof and Pointer. Package names are not counted.
+	if n := Uses(pkg, "unsafe"); n != 3 {
+		t.Errorf("want 3 uses of unsafe, got %v", n)
+	}
+	// TypeOf, Kind and Int.
+	if n := Uses(pkg, "reflect"); n != 3 {
+		t.Errorf("want 3 uses of reflect, got %v", n)
+	}
+}
+
+func Test_CgoFiles(t *testing.T) {
+	pkg := &packages.Package{
+		GoFiles:         []string{"/src/a/a.go", "/src/a/c.go"},
+		CompiledGoFiles: []string{"/src/a/a.go", "/cache/01-d", "/cache/02-d", "/cache/03-d"},
+	}
+	if n := CgoFiles(pkg); n != 1 {
+		t.Fatalf("want 1, got %v", n)
+	}
+}
@@ -0,0 +1,133 @@
+// Package conflict finds the conflicts which prevent renaming definitions of a package.
+package conflict
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"iter"
+
+	"github.com/mkch/goingbad/internal/renamer/scope"
+	"github.com/mkch/goingbad/internal/renamer/selection"
+)
+
+// Kind is the kind of a [Conflict].
+type Kind int
+
+const (
+	// DefConflict is that the new name is already defined in the scope of
+	// the definition, or the definition would shadow the uses of the new name.
+	DefConflict Kind = iota
+	// UseConflict is that a use of the definition would refer to another
+	// declaration of the new name.
+	UseConflict
+	// SelectorConflict is that the new name of a field or method clashes with
+	// a field or method of the types it belongs to or is promoted to.
+	SelectorConflict
+	// EmbeddedConflict is that the new name of a type, which is also the name of
+	// the fields embedding it, clashes with a field or method of the embedding structs.
+	EmbeddedConflict
+)
+
+func (k Kind) String() string {
+	switch k {
+	case DefConflict:
+		return "definition conflict"
+	case UseConflict:
+		return "use conflict"
+	case SelectorConflict:
+		return "selector conflict"
+	case EmbeddedConflict:
+		return "embedded field conflict"
+	}
+	return "unknown conflict"
+}
+
+// Conflict is a reason why renaming a definition would change the behavior of the program.
+type Conflict struct {
+	Kind Kind
+	// Pos is the position of the existing definition of the new name, the use which would
+	// refer to another declaration, or the field or method which would clash.
+	// It is the position of the renamed definition if no other position is more specific.
+	Pos token.Pos
+	// Type is the receiver or struct type of the field or method which would clash.
+	// It is nil if Kind is not SelectorConflict.
+	Type types.Type
+}
+
+// Checker checks whether the definitions of a package can be renamed.
+// It reflects the renamings done to Info and Sel.
+type Checker struct {
+	Pkg  *types.Package
+	Info *scope.Info
+	Sel  *selection.Selection
+	// MethodGroup is the methods which must be renamed together, by the positions of their names.
+	MethodGroup map[token.Pos][]selection.Method
+}
+
+// Scoped returns the conflicts of renaming scoped identifier id to newName.
+// Scoped identifiers are identifiers that are not fields nor methods.
+func (c *Checker) Scoped(id *ast.Ident, newName string) iter.Seq[Conflict] {
+	return func(yield func(Conflict) bool) {
+		if !c.Sel.CanRenameEmbedded(id.Pos(), id.Name, newName) {
+			if !yield(Conflict{Kind: EmbeddedConflict, Pos: id.Pos()}) {
+				return
+			}
+		}
+		defScope := c.Info.DefScopes[id]
+		if defScope == nil {
+			return
+		}
+		if !defScope.CanDef(newName, id.Pos()) {
+			pos := defScope.LookupDef(newName)
+			if !pos.IsValid() {
+				pos = id.Pos() // Would shadow a use, or defined in another file.
+			}
+			if !yield(Conflict{Kind: DefConflict, Pos: pos}) {
+				return
+			}
+		}
+		for _, use := range c.Info.Uses.Lookup(id.Name) {
+			if use.Def == id.Pos() && !use.UseScope.CanUse(newName, use.Use, defScope) {
+				if !yield(Conflict{Kind: UseConflict, Pos: use.Use}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FieldMethod returns the conflicts of renaming field or method id to newName,
+// including the methods which must be renamed together with it.
+func (c *Checker) FieldMethod(id *ast.Ident, newName string) iter.Seq[Conflict] {
+	return func(yield func(Conflict) bool) {
+		positions := []token.Pos{id.Pos()}
+		if methods := c.MethodGroup[id.Pos()]; len(methods) > 0 {
+			positions = positions[:0]
+			for _, mtd := range methods {
+				positions = append(positions, mtd.ID.Pos())
+			}
+		}
+		for _, pos := range positions {
+			t := c.Sel.Clash(id.Name, pos, newName)
+			if t == nil {
+				continue
+			}
+			conflict := Conflict{Kind: SelectorConflict, Pos: pos, Type: t}
+			if obj, _, _ := types.LookupFieldOrMethod(t, true, c.Pkg, newName); obj != nil {
+				conflict.Pos = obj.Pos()
+			}
+			if !yield(conflict) {
+				return
+			}
+		}
+	}
+}
+
+// Any returns whether conflicts is not empty.
+func Any(conflicts iter.Seq[Conflict]) bool {
+	for range conflicts {
+		return true
+	}
+	return false
+}
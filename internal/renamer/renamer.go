@@ -2,16 +2,23 @@
 package renamer
 
 import (
+	"cmp"
+	"errors"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"maps"
 	"regexp"
+	"runtime/debug"
+	"slices"
 	"strings"
 
+	"github.com/mkch/gg"
 	"github.com/mkch/goingbad/internal/idgen"
+	"github.com/mkch/goingbad/internal/renamer/conflict"
 	"github.com/mkch/goingbad/internal/renamer/scope"
 	"github.com/mkch/goingbad/internal/renamer/selection"
+	"github.com/mkch/goingbad/sigcompat"
 	"github.com/mkch/iter2"
 	"golang.org/x/tools/go/packages"
 )
@@ -21,21 +28,23 @@ type defRenamer struct {
 	info        *scope.Info
 	sel         *selection.Selection
 	methodGroup map[token.Pos][]selection.Method
+	conflicts   *conflict.Checker
 	// The type of "*testing.T".
 	// Used to match the argument of a testing function.
 	// nil if "testing" package is not imported by this package.
 	asterisk_testing_dot_T types.Type
 }
 
-func newDefRenamer(pkg *packages.Package) *defRenamer {
+func newDefRenamer(pkg *packages.Package, cache *sigcompat.Cache) *defRenamer {
 	renamer := &defRenamer{sel: selection.New(pkg)}
 	renamer.methodGroup = maps.Collect(iter2.Map2(
-		maps.All(selection.GroupMethods(pkg.TypesInfo.Defs)),
+		maps.All(selection.GroupMethods(pkg.TypesInfo.Defs, cache)),
 		func(k *types.Func, v []selection.Method) (token.Pos, []selection.Method) {
 			pos := k.Pos()
 			return pos, v
 		}))
 	renamer.pkgScope, renamer.info = scope.PackageScope(pkg.Types, pkg.TypesInfo)
+	renamer.conflicts = &conflict.Checker{Pkg: pkg.Types, Info: renamer.info, Sel: renamer.sel, MethodGroup: renamer.methodGroup}
 
 	for _, imported := range pkg.Types.Imports() {
 		if imported.Path() == "testing" {
@@ -54,35 +63,138 @@ func RenameUsedExports(pkg *packages.Package, renamed map[token.Pos]string) {
 	}
 }
 
-func Rename(pkg *packages.Package, idGen *idgen.Generator, renameExported bool, renamedExports map[token.Pos]string, keep func(pkg, name string) bool) {
-	var renamer = newDefRenamer(pkg)
+// Options controls how the identifiers of a package are renamed.
+type Options struct {
+	// IDGen generates the new names.
+	IDGen *idgen.Generator
+	// ExportedIDGen generates the new names of exported identifiers. IDGen is used if nil.
+	ExportedIDGen *idgen.Generator
+	// RenameExported is whether exported identifiers are renamed.
+	RenameExported bool
+	// RenamedExports receives the new names of renamed exported identifiers,
+	// keyed by their definition positions. Required if RenameExported is true.
+	RenamedExports map[token.Pos]string
+	// Keep returns whether name declared in package pkg should be kept.
+	Keep func(pkg, name string) bool
+	// KeepDef returns whether the definition id should be kept, in addition to Keep.
+	// Nil means no additional definitions are kept.
+	KeepDef func(id *ast.Ident) bool
+	// Secure returns whether name declared in package pkg is security-critical.
+	// Security-critical identifiers, including these annotated with
+	// //goingbad:secure, are renamed to long random names.
+	Secure func(pkg, name string) bool
+	// JSONTags is whether to add a json tag with the original name to
+	// renamed exported struct fields, so their json keys remain the same.
+	JSONTags bool
+	// FixedLayout returns whether the layout of struct type st must be kept,
+	// because it is reinterpreted by unsafe code. Declarations of these types
+	// are never rewritten. Nil means no such types.
+	FixedLayout func(st *types.Struct) bool
+	// MaxPanics is the maximum number of definitions skipped due to recovered panics
+	// before the package is given up. Negative means no limit.
+	MaxPanics int
+	// Signatures caches the comparisons of method signatures.
+	// It can be shared by the packages loaded together. Nil means no caching.
+	Signatures *sigcompat.Cache
+}
+
+// Renamed is a renamed definition.
+type Renamed struct {
+	ID      *ast.Ident   // The identifier of the definition, which has the new name.
+	Object  types.Object // The object defined by ID. Nil if ID is the symbolic variable of a type switch.
+	OldName string
+	Secure  bool // Whether the identifier is security-critical, see [Options.Secure].
+}
+
+// Panic is a panic recovered while renaming a definition.
+type Panic struct {
+	Pos   token.Pos // The position of the definition.
+	Name  string    // The name of the definition before renaming.
+	Value any       // The value passed to panic.
+	Stack []byte    // The stack trace of the panic.
+}
+
+// ErrTooManyPanics is returned by [Rename] if there are more panics than [Options.MaxPanics].
+var ErrTooManyPanics = errors.New("too many panics recovered")
+
+// recovered calls f and returns the value and the stack trace of the panic recovered, if any.
+func recovered(f func()) (value any, stack []byte) {
+	defer func() {
+		if value = recover(); value != nil {
+			stack = debug.Stack()
+		}
+	}()
+	f()
+	return
+}
+
+// Rename renames the identifiers defined in pkg.
+// The renamed definitions are returned in the order of their positions.
+//
+// A panic while renaming a definition is recovered, and the definition is skipped.
+// The recovered panics are returned in panics. If there are more than opts.MaxPanics,
+// Rename gives up with [ErrTooManyPanics]. Pkg is left partially renamed then, and
+// must not be written. Nothing is added to opts.RenamedExports in that case.
+func Rename(pkg *packages.Package, opts *Options) (result []Renamed, panics []Panic, err error) {
+	var renamer = newDefRenamer(pkg, opts.Signatures)
 
 	renamed := make(map[token.Pos]string)
+	exports := make(map[token.Pos]string)
+
+	secureIDs := secureIdents(pkg.Syntax)
 
-	for id, def := range pkg.TypesInfo.Defs {
+	// Fields of the struct types in type sets are part of the constraints.
+	typeSetFields := make(gg.Set[types.Object])
+	for _, st := range selection.TypeSetStructs(pkg) {
+		for field := range st.Fields() {
+			typeSetFields.Add(field)
+		}
+	}
+
+	var fields map[*ast.Ident]*ast.FieldList
+	// The original names of the renamed fields to add json tags to.
+	tagged := make(map[*ast.Ident]string)
+	if opts.JSONTags && opts.RenameExported {
+		fields = structFields(pkg.Syntax, func(st *ast.StructType) bool {
+			s, _ := pkg.TypesInfo.TypeOf(st).(*types.Struct)
+			return opts.FixedLayout != nil && opts.FixedLayout(s)
+		})
+	}
+
+	// Definitions are renamed in the order of their positions, so the result
+	// does not depend on the iteration order of maps.
+	defs := slices.SortedFunc(maps.Keys(pkg.TypesInfo.Defs), func(a, b *ast.Ident) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	exportedIDGen := opts.IDGen
+	if opts.ExportedIDGen != nil {
+		exportedIDGen = opts.ExportedIDGen
+	}
+	renameDef := func(id *ast.Ident) {
+		def := pkg.TypesInfo.Defs[id]
 		if _, alreadyRenamed := renamed[id.Pos()]; alreadyRenamed {
-			continue
+			return
 		}
 		if id.Name == "." || id.Name == "_" {
-			continue
+			return
 		}
-		if keep(pkg.PkgPath, id.Name) {
-			continue
+		if opts.Keep(pkg.PkgPath, id.Name) || opts.KeepDef != nil && opts.KeepDef(id) {
+			return
 		}
 		var exported bool
 		var rename = renamer.RenameScoped
 		if def == nil { // symbolic or package name in package clause.
 			if !renamer.isSymbolic(id) {
-				continue
+				return
 			}
 		} else {
-			if isInitFunc(def) {
-				continue
+			if isInitFunc(def) || isMainFunc(def) {
+				return
 			} else if def.Parent() == nil { // methods and struct fields.
 				if isTestFunc(pkg.Fset, renamer.asterisk_testing_dot_T, def) {
-					continue // Do not rename test function.
+					return // Do not rename test function.
 				} else if field, _ := def.(*types.Var); field != nil && field.Embedded() {
-					continue // Do not rename embedded fields. They are renamed with their types.
+					return // Do not rename embedded fields. They are renamed with their types.
+				} else if typeSetFields.Contains(def) {
+					return
 				}
 				rename = renamer.RenameFieldMethod
 				exported = id.IsExported()
@@ -93,52 +205,66 @@ func Rename(pkg *packages.Package, idGen *idgen.Generator, renameExported bool,
 				exported = def.Parent() == pkg.Types.Scope() && id.IsExported()
 			}
 		}
-		if exported && !renameExported {
-			continue
+		if exported && !opts.RenameExported {
+			return
 		}
+		secure := secureIDs.Contains(id) || opts.Secure != nil && opts.Secure(pkg.PkgPath, id.Name)
 		var next func() string
-		if exported {
-			next = idGen.NewExported(nil)
-		} else {
-			next = idGen.NewUnexported(nil)
+		switch {
+		case exported && secure:
+			next = exportedIDGen.NewRandomExported(secureKey(pkg, id), secureLength, nil)
+		case exported:
+			next = exportedIDGen.NewExported(nil)
+		case secure:
+			next = opts.IDGen.NewRandomUnexported(secureKey(pkg, id), secureLength, nil)
+		default:
+			next = opts.IDGen.NewUnexported(nil)
 		}
+		oldName := id.Name
 		for {
 			newName := next()
 			if id.Name == newName {
 				break
 			}
-			if result := rename(id, newName); len(result) > 0 {
-				for _, r := range result {
+			if ids := rename(id, newName); len(ids) > 0 {
+				for _, r := range ids {
 					renamed[r.Pos()] = newName
+					result = append(result, Renamed{r, pkg.TypesInfo.Defs[r], oldName, secure})
 					if exported {
-						renamedExports[r.Pos()] = newName
+						exports[r.Pos()] = newName
 					}
 				}
+				if fields[id] != nil && exported {
+					tagged[id] = oldName
+				}
 				break
 			}
 		}
 	}
+	for _, id := range defs {
+		name := id.Name
+		value, stack := recovered(func() { renameDef(id) })
+		if value == nil {
+			continue
+		}
+		panics = append(panics, Panic{Pos: id.Pos(), Name: name, Value: value, Stack: stack})
+		if opts.MaxPanics >= 0 && len(panics) > opts.MaxPanics {
+			return nil, panics, ErrTooManyPanics
+		}
+	}
 
 	for id, use := range pkg.TypesInfo.Uses {
 		if newName, ok := renamed[use.Pos()]; ok {
 			id.Name = newName
 		}
 	}
-}
-
-func (renamer *defRenamer) canRenameScoped(name string, defPos token.Pos, defScope scope.Scope, newName string) bool {
-	if !defScope.CanDef(newName, defPos) {
-		return false
-	}
-	for _, use := range renamer.info.Uses.Lookup(name) {
-		if use.Def != defPos {
-			continue
-		}
-		if !use.UseScope.CanUse(newName, use.Use, defScope) {
-			return false
-		}
+	// Fields are split after the uses are renamed, so the copies of their types have the new names.
+	for _, id := range slices.SortedFunc(maps.Keys(tagged), func(a, b *ast.Ident) int { return cmp.Compare(a.Pos(), b.Pos()) }) {
+		addJSONTag(splitField(fields[id], id, pkg.TypesInfo), tagged[id])
 	}
-	return true
+	maps.Copy(opts.RenamedExports, exports)
+	slices.SortFunc(result, func(a, b Renamed) int { return cmp.Compare(a.ID.Pos(), b.ID.Pos()) })
+	return
 }
 
 // isSymbolic returns whether a definition id denotes to a symbolic variable.
@@ -153,14 +279,10 @@ func (renamer *defRenamer) isSymbolic(def *ast.Ident) (symbolic bool) {
 //
 // Scoped identifiers are identifiers that are not fields nor methods.
 func (renamer *defRenamer) RenameScoped(id *ast.Ident, newName string) (renamed []*ast.Ident) {
-	if !renamer.sel.CanRenameEmbedded(id.Pos(), id.Name, newName) {
+	if conflict.Any(renamer.conflicts.Scoped(id, newName)) {
 		return
 	}
-	// TODO: Here
 	scope := renamer.info.DefScopes[id]
-	if !renamer.canRenameScoped(id.Name, id.Pos(), scope, newName) {
-		return
-	}
 
 	scope.RenameChildren(id.Name, id.Pos(), newName)
 	renamer.info.Uses.Rename(id.Name, id.Pos(), newName)
@@ -171,13 +293,11 @@ func (renamer *defRenamer) RenameScoped(id *ast.Ident, newName string) (renamed
 }
 
 func (renamer *defRenamer) RenameFieldMethod(id *ast.Ident, newName string) (renamed []*ast.Ident) {
+	if conflict.Any(renamer.conflicts.FieldMethod(id, newName)) {
+		return
+	}
 	// method
 	if methodsImplSame := renamer.methodGroup[id.Pos()]; len(methodsImplSame) > 0 {
-		for _, mtd := range methodsImplSame {
-			if !renamer.sel.CanRenameFieldMethod(id.Name, mtd.ID.Pos(), newName) {
-				return
-			}
-		}
 		for _, mtd := range methodsImplSame {
 			renamer.sel.RenameFieldMethod(mtd.ID.Name, mtd.ID.Pos(), newName)
 			mtd.ID.Name = newName
@@ -186,9 +306,6 @@ func (renamer *defRenamer) RenameFieldMethod(id *ast.Ident, newName string) (ren
 		return
 	}
 	// field
-	if !renamer.sel.CanRenameFieldMethod(id.Name, id.Pos(), newName) {
-		return
-	}
 	renamer.sel.RenameFieldMethod(id.Name, id.Pos(), newName)
 	id.Name = newName
 	renamed = append(renamed, id)
@@ -244,3 +361,13 @@ func isInitFunc(obj types.Object) bool {
 	}
 	return signature.Params() == nil
 }
+
+// isMainFunc returns true if obj is the main function of package main.
+// The package path does not matter, main packages can be anywhere.
+func isMainFunc(obj types.Object) bool {
+	f, ok := obj.(*types.Func)
+	if !ok || f.Name() != "main" || f.Pkg() == nil || f.Pkg().Name() != "main" {
+		return false
+	}
+	return f.Parent() == f.Pkg().Scope()
+}
@@ -0,0 +1,193 @@
+package renamer
+
+import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mkch/goingbad/internal/idgen"
+	"golang.org/x/tools/go/packages"
+)
+
+// Test_Rename_generic renames parameterized aliases and range-over-func iterators,
+// and checks that the result still type checks.
+func Test_Rename_generic(t *testing.T) {
+	pkg, err := loadPackage("testdata/generic.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, err := Rename(pkg, &Options{
+		IDGen: idgen.NewGenerator("a", "b", "c", "d"),
+		Keep:  func(pkg, name string) bool { return false },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var renamed []string
+	for _, r := range result {
+		renamed = append(renamed, r.OldName)
+	}
+	for _, name := range []string{"set", "pair", "pairs", "list", "listAlias", "sequence", "all", "keys", "entries", "total", "key", "value"} {
+		if !slices.Contains(renamed, name) {
+			t.Errorf("%v is not renamed", name)
+		}
+	}
+
+	var out strings.Builder
+	if err := format.Node(&out, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generic.go", out.String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("generic", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%v", err, out.String())
+	}
+}
+
+// Test_Rename_typeParam renames the fields and methods selected through type parameters,
+// and checks that the result still type checks.
+func Test_Rename_typeParam(t *testing.T) {
+	pkg, err := loadPackage("testdata/typeparam.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = Rename(pkg, &Options{
+		IDGen: idgen.NewGenerator("a", "b", "c", "d"),
+		Keep:  func(pkg, name string) bool { return false },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := format.Node(&out, pkg.Fset, pkg.Syntax[0]); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "typeparam.go", out.String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("typeparam", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%v", err, out.String())
+	}
+}
+
+// Test_Rename_main keeps the main function of package main, but not the methods named main.
+func Test_Rename_main(t *testing.T) {
+	pkg, err := loadPackage("testdata/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, err := Rename(pkg, &Options{
+		IDGen: idgen.NewGenerator("a", "b", "c", "d"),
+		Keep:  func(pkg, name string) bool { return false },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var renamed []string
+	for _, r := range result {
+		renamed = append(renamed, r.OldName)
+	}
+	if want := []string{"command", "main", "helper"}; !slices.Equal(renamed, want) {
+		t.Fatalf("want %v renamed, got %v", want, renamed)
+	}
+	if name := pkg.Syntax[0].Decls[3].(*ast.FuncDecl).Name.Name; name != "main" {
+		t.Fatalf("main function is renamed to %v", name)
+	}
+}
+
+// Test_Rename_panic skips the definitions panicking, and gives up the package
+// if there are too many of them.
+func Test_Rename_panic(t *testing.T) {
+	keep := func(pkg, name string) bool {
+		if name == "command" || name == "helper" {
+			panic(name)
+		}
+		return false
+	}
+	var tests = []struct {
+		name      string
+		maxPanics int
+		renamed   []string
+		err       error
+	}{
+		{"unlimited", -1, []string{"main"}, nil},
+		{"under limit", 2, []string{"main"}, nil},
+		{"over limit", 1, nil, ErrTooManyPanics},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := loadPackage("testdata/main.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, panics, err := Rename(pkg, &Options{
+				IDGen:     idgen.NewGenerator("a", "b", "c", "d"),
+				Keep:      keep,
+				MaxPanics: tt.maxPanics,
+			})
+			if err != tt.err {
+				t.Fatalf("want error %v, got %v", tt.err, err)
+			}
+			var renamed []string
+			for _, r := range result {
+				renamed = append(renamed, r.OldName)
+			}
+			if !slices.Equal(renamed, tt.renamed) {
+				t.Fatalf("want %v renamed, got %v", tt.renamed, renamed)
+			}
+			var panicked []string
+			for _, p := range panics {
+				if p.Value != p.Name || len(p.Stack) == 0 {
+					t.Fatalf("bad panic %#v", p)
+				}
+				panicked = append(panicked, p.Name)
+			}
+			if want := []string{"command", "helper"}; !slices.Equal(panicked, want) {
+				t.Fatalf("want %v panicked, got %v", want, panicked)
+			}
+		})
+	}
+}
+
+func loadPackage(filename string) (*packages.Package, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+	if err != nil {
+		return nil, err
+	}
+	return &packages.Package{
+		Name:      pkg.Name(),
+		PkgPath:   pkg.Path(),
+		Fset:      fset,
+		Syntax:    []*ast.File{f},
+		Types:     pkg,
+		TypesInfo: info,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package renamer
+
+import (
+	"go/ast"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// secureDirective marks the identifiers of the declaration that follows
+// as security-critical.
+const secureDirective = "//goingbad:secure"
+
+// secureLength is the number of elements of the new names of security-critical identifiers.
+const secureLength = 32
+
+// secureKey returns the key of the random new name of id, a definition of pkg.
+// The key does not depend on the other definitions or the directory of pkg, so the
+// new name is the same in every run, with or without the other packages.
+func secureKey(pkg *packages.Package, id *ast.Ident) string {
+	position := pkg.Fset.Position(id.Pos())
+	return pkg.PkgPath + " " + filepath.Base(position.Filename) + ":" + strconv.Itoa(position.Offset)
+}
+
+// hasSecureDirective returns whether doc contains secureDirective.
+func hasSecureDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	return slices.ContainsFunc(doc.List, func(c *ast.Comment) bool { return c.Text == secureDirective })
+}
+
+// secureIdents returns the identifiers declared with secureDirective in files.
+func secureIdents(files []*ast.File) gg.Set[*ast.Ident] {
+	result := make(gg.Set[*ast.Ident])
+	add := func(names ...*ast.Ident) {
+		for _, name := range names {
+			result.Add(name)
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FuncDecl:
+				if hasSecureDirective(node.Doc) {
+					add(node.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range node.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if hasSecureDirective(node.Doc) || hasSecureDirective(spec.Doc) {
+							add(spec.Name)
+						}
+					case *ast.ValueSpec:
+						if hasSecureDirective(node.Doc) || hasSecureDirective(spec.Doc) {
+							add(spec.Names...)
+						}
+					}
+				}
+			case *ast.Field:
+				if hasSecureDirective(node.Doc) {
+					add(node.Names...)
+				}
+			}
+			return true
+		})
+	}
+	return result
+}
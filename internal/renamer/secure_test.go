@@ -0,0 +1,46 @@
+package renamer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mkch/iter2"
+)
+
+func Test_secureIdents(t *testing.T) {
+	const src = `package a
+
+//goingbad:secure
+func checkLicense() {}
+
+func f() {
+	//goingbad:secure
+	var key, salt = 1, 2
+	_, _ = key, salt
+}
+
+type (
+	//goingbad:secure
+	tamper int
+	other  int
+)
+
+type T struct {
+	//goingbad:secure
+	token string
+	name  string
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Sorted(iter2.Map(maps.Keys(secureIdents([]*ast.File{f})), func(id *ast.Ident) string { return id.Name }))
+	if want := []string{"checkLicense", "key", "salt", "tamper", "token"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
@@ -347,6 +347,7 @@ type fieldMethodMap map[token.Pos]*chainedType
 // chainedType is a type with it's embeders.
 type chainedType struct {
 	t        typ
+	types    types.Type     // The type t is created from.
 	embeders []*chainedType // The types has t as their embedded fields.
 }
 
@@ -367,7 +368,7 @@ func addType(tm typeMap, cm compositeMap, fmm fieldMethodMap, t types.Type) *cha
 			return t
 		}
 		chainType := newDefined(nil)
-		ret := &chainedType{t: chainType}
+		ret := &chainedType{t: chainType, types: t}
 		tm[k] = ret
 		name := t.Obj()
 		u := addType(tm, cm, fmm, name.Type().Underlying()).Type()
@@ -385,6 +386,8 @@ func addType(tm typeMap, cm compositeMap, fmm fieldMethodMap, t types.Type) *cha
 			k.Pos = elem.Obj().Pos()
 		case *types.Struct, *types.Interface:
 			k.Pos = cm[elem]
+		case *types.TypeParam:
+			return nil // Such as the *E in constraint interface{ *E }, which has no fields or methods.
 		default:
 			panic("invalid base type")
 		}
@@ -392,7 +395,7 @@ func addType(tm typeMap, cm compositeMap, fmm fieldMethodMap, t types.Type) *cha
 			return t
 		}
 		chainType := newPtr(nil)
-		ret := &chainedType{t: chainType}
+		ret := &chainedType{t: chainType, types: t}
 		tm[k] = ret
 		chainType.base = addType(tm, cm, fmm, elem).Type()
 		return ret
@@ -402,7 +405,7 @@ func addType(tm typeMap, cm compositeMap, fmm fieldMethodMap, t types.Type) *cha
 			return t
 		}
 		chainType := newStruct()
-		ret := &chainedType{t: chainType}
+		ret := &chainedType{t: chainType, types: t}
 		tm[k] = ret
 		for f := range t.Fields() {
 			t := f.Type()
@@ -434,7 +437,7 @@ func addType(tm typeMap, cm compositeMap, fmm fieldMethodMap, t types.Type) *cha
 			return t
 		}
 		chainType := newIface()
-		ret := &chainedType{t: chainType}
+		ret := &chainedType{t: chainType, types: t}
 		tm[k] = ret
 		for mtd := range t.ExplicitMethods() {
 			chainType.AddMethod(mtd.Name(), mtd.Signature())
@@ -586,6 +589,12 @@ func (sel *Selection) RenameEmbedded(def token.Pos, newName string) {
 
 // canRenameSelTo returns whether a method or field in t can be renamed to new name.
 func canRenameSelTo(t *chainedType, name, newName string) bool {
+	return selClash(t, name, newName) == nil
+}
+
+// selClash returns t or the embeder of t which has a field or method named newName,
+// if the method or field name in t can not be renamed to newName. The result is nil otherwise.
+func selClash(t *chainedType, name, newName string) *chainedType {
 	var face *iface
 	if defined, _ := t.t.(*defined); defined != nil {
 		face, _ = defined.underlying.(*iface)
@@ -593,25 +602,37 @@ func canRenameSelTo(t *chainedType, name, newName string) bool {
 		face, _ = t.t.(*iface)
 	}
 	if face != nil {
-		return face.CanRenameTo(name, newName)
+		if face.CanRenameTo(name, newName) {
+			return nil
+		}
+		return t
 	}
 
 	if HasName(t.t, newName) {
-		return false
+		return t
 	}
 	for _, t := range t.embeders {
 		if HasName(t.t, newName) {
-			return false
+			return t
 		}
 	}
-	return true
+	return nil
 }
 
 // CanRenameFieldMethod returns whether a field or method defined at a specified position
 // can be renamed to a new name.
 func (sel *Selection) CanRenameFieldMethod(name string, pos token.Pos, newName string) bool {
-	t := sel.fmm[pos]
-	return canRenameSelTo(t, name, newName)
+	return sel.Clash(name, pos, newName) == nil
+}
+
+// Clash returns the type which has a field or method named newName, the receiver or struct type
+// of the field or method defined at a specified position or a struct embedding it, if the field
+// or method can not be renamed to newName. The result is nil otherwise.
+func (sel *Selection) Clash(name string, pos token.Pos, newName string) types.Type {
+	if t := selClash(sel.fmm[pos], name, newName); t != nil {
+		return t.types
+	}
+	return nil
 }
 
 // RenameFieldMethod renames a field or method defined at a specified position to a new name.
@@ -646,7 +667,7 @@ func renamePtrSel(t *ptr, name, newName string) bool {
 	case *defined:
 		if t.ptrMethods.Contains(name) {
 			t.ptrMethods.Delete(name)
-			t.ptrMethods.Add(name)
+			t.ptrMethods.Add(newName)
 			return true
 		}
 		return renameDefinedSel(t, name, newName)
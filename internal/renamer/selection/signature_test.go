@@ -12,68 +12,19 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mkch/goingbad/sigcompat"
 	"github.com/mkch/iter2"
 )
 
-func Test_implSameMethod(t *testing.T) {
-	pkg, _ := loadPackage()
-
-	f1 := lookupMethod(pkg, "t1", 0)
-	f2 := lookupMethod(pkg, "t2", 0)
-	f3 := lookupMethod(pkg, "t3", 0)
-	f4 := lookupMethod(pkg, "t4", 0)
-	f5 := lookupMethod(pkg, "t5", 0)
-	f6 := lookupMethod(pkg, "t6", 0)
-	f7 := lookupMethod(pkg, "t7", 0)
-	f8 := lookupMethod(pkg, "t8", 0)
-	f9 := lookupMethod(pkg, "t9", 0)
-	f10 := lookupMethod(pkg, "t10", 0)
-	f11 := lookupMethod(pkg, "t11", 0)
-	f12 := lookupMethod(pkg, "t12", 0)
-	f13 := lookupMethod(pkg, "t13", 0)
-	f14 := lookupMethod(pkg, "t14", 0)
-	f15 := lookupMethod(pkg, "t15", 0)
-	f16 := lookupMethod(pkg, "t16", 0)
-	f17 := lookupMethod(pkg, "t17", 0)
-	f18 := lookupMethod(pkg, "t18", 0)
-	f19 := lookupMethod(pkg, "t19", 0)
-	f20 := lookupMethod(pkg, "t20", 0)
-	f21 := lookupMethod(pkg, "t21", 0)
-	f22 := lookupMethod(pkg, "t22", 0)
-	f23 := lookupMethod(pkg, "t23", 0)
-	f24 := lookupMethod(pkg, "t24", 0)
-	f25 := lookupMethod(pkg, "t25", 0)
-
-	assertImplSameMethod(t, f1, f2, true, "simple match")
-	assertImplSameMethod(t, f1, f3, false, "variadic vs non-variadic")
-	assertImplSameMethod(t, f3, f4, true, "variadic match")
-	assertImplSameMethod(t, f5, f6, false, "defined types are unique")
-	assertImplSameMethod(t, f5, f7, false, "defined types are unique")
-	assertImplSameMethod(t, f6, f7, true, "alias match")
-	assertImplSameMethod(t, f5, f8, false, "defined types are unique")
-	assertImplSameMethod(t, f6, f8, false, "defined types are unique")
-	assertImplSameMethod(t, f1, f9, false, "not satisfies")
-	assertImplSameMethod(t, f10, f11, true, "Type terms intersect")
-	assertImplSameMethod(t, f10, f1, false, "defined types are unique")
-	assertImplSameMethod(t, f12, f1, false, "simple mismatch: argument")
-	assertImplSameMethod(t, f13, f1, false, "simple mismatch: return value")
-	assertImplSameMethod(t, f14, f9, false, "not satisfies")
-	assertImplSameMethod(t, f15, f16, false, "defined types are unique")
-	assertImplSameMethod(t, f1, f17, true, "satisfies")
-	assertImplSameMethod(t, f18, f18, true, "no param nor result")
-	assertImplSameMethod(t, f19, f20, true, "identical structs")
-	assertImplSameMethod(t, f19, f21, false, "tags diff")
-	assertImplSameMethod(t, f1, f21, false, "param diff")
-	assertImplSameMethod(t, f19, f22, true, "potentially identical structs")
-	assertImplSameMethod(t, f19, f23, false, "tags diff")
-	assertImplSameMethod(t, f21, f23, true, "potentially identical structs")
-	assertImplSameMethod(t, f24, f25, true, "potentially identical interfaces")
-
-}
-
 func Test_GroupMethods(t *testing.T) {
 	pkg, info := loadPackage()
-	implMap := GroupMethods(info.Defs)
+	implMap := GroupMethods(info.Defs, nil)
+	cached := GroupMethods(info.Defs, sigcompat.NewCache())
+	for f, group := range implMap {
+		if !slices.Equal(group, cached[f]) {
+			t.Errorf("%v: cached group %v, want %v", f, cached[f], group)
+		}
+	}
 
 	var equal = func(s1, s2 []*types.Func) bool {
 		s1 = slices.Clone(s1)
@@ -172,15 +123,6 @@ func Test_GroupMethods(t *testing.T) {
 
 }
 
-// assertImplSameMethod is a helper for testing MayImplSameMethod.
-func assertImplSameMethod(t *testing.T, mtd1, mtd2 *types.Func, expected bool, msg string) {
-	t.Helper()
-	actual := implSameMethod(mtd1, mtd2)
-	if actual != expected {
-		t.Errorf("MayImplSameMethod got %v, expected %v. %s", actual, expected, msg)
-	}
-}
-
 func lookupMethod(pkg *types.Package, typeName string, mtdIndex int) *types.Func {
 	return lookupType(pkg, typeName).(*types.Named).Method(mtdIndex)
 }
@@ -203,3 +145,46 @@ func loadPackage() (pkg *types.Package, info *types.Info) {
 	}
 	return
 }
+
+// largeMethodsSource returns the source of a package with n types, each of which
+// has the same set of method names with one of a few signatures.
+func largeMethodsSource(n int) string {
+	var b strings.Builder
+	b.WriteString("package large\n\n")
+	signatures := []string{"(int) int", "(string) error", "(...any)", "([]byte) (int, error)"}
+	for i := range n {
+		fmt.Fprintf(&b, "type t%d struct{}\n\n", i)
+		for j := range 10 {
+			fmt.Fprintf(&b, "func (t%d) M%d%s { panic(0) }\n", i, j, signatures[(i+j)%len(signatures)])
+		}
+		b.WriteString("\n")
+	}
+	for j := range 10 {
+		fmt.Fprintf(&b, "type i%d interface{ M%d%s }\n", j, j, signatures[j%len(signatures)])
+	}
+	return b.String()
+}
+
+func BenchmarkGroupMethods(b *testing.B) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "large.go", largeMethodsSource(1000), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	if _, err = conf.Check("large", fset, []*ast.File{f}, info); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("nocache", func(b *testing.B) {
+		for range b.N {
+			GroupMethods(info.Defs, nil)
+		}
+	})
+	b.Run("cache", func(b *testing.B) {
+		cache := sigcompat.NewCache() // Shared by iterations, as by packages in a run.
+		for range b.N {
+			GroupMethods(info.Defs, cache)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package selection
+
+import (
+	"cmp"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeSetStructs returns the positions of the struct types in pkg whose fields must keep
+// their names, because they are, or are identical to, the struct types in the type sets
+// of the constraints of type parameters, such as ~struct{ x, y int }.
+//
+// Field names are part of the identity of struct types. Renaming the fields of
+// the struct types separately would make the type arguments no longer satisfy
+// the constraints, and conversions through the core types of type parameters invalid,
+// so they are conservatively skipped. Only the type parameters declared in pkg are considered.
+func TypeSetStructs(pkg *packages.Package) map[token.Pos]*types.Struct {
+	structs := make(map[*types.Struct]token.Pos)
+	for expr, tv := range pkg.TypesInfo.Types {
+		if st, ok := tv.Type.(*types.Struct); ok {
+			if _, ok := expr.(*ast.StructType); ok {
+				structs[st] = expr.Pos()
+			}
+		}
+	}
+
+	var terms []*types.Struct
+	visited := make(gg.Set[*types.Interface])
+	var addTerms func(t types.Type)
+	addTerms = func(t types.Type) {
+		switch t := types.Unalias(t).Underlying().(type) {
+		case *types.Interface:
+			if visited.Contains(t) {
+				return
+			}
+			visited.Add(t)
+			for embedded := range t.EmbeddedTypes() {
+				addTerms(embedded)
+			}
+		case *types.Union:
+			for term := range t.Terms() {
+				addTerms(term.Type())
+			}
+		case *types.Struct:
+			terms = append(terms, t)
+		}
+	}
+	// Iterate in the order of positions, so the result does not depend on the order of maps.
+	defs := slices.SortedFunc(maps.Keys(pkg.TypesInfo.Defs), func(a, b *ast.Ident) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	for _, id := range defs {
+		if tn, ok := pkg.TypesInfo.Defs[id].(*types.TypeName); ok {
+			if tp, ok := tn.Type().(*types.TypeParam); ok {
+				addTerms(tp.Constraint())
+			}
+		}
+	}
+
+	result := make(map[token.Pos]*types.Struct)
+	var keep func(st *types.Struct)
+	keep = func(st *types.Struct) {
+		pos, ok := structs[st]
+		if !ok || result[pos] != nil {
+			return
+		}
+		result[pos] = st
+		// Nested struct types are part of the identity, too.
+		for field := range st.Fields() {
+			if nested, ok := types.Unalias(field.Type()).(*types.Struct); ok {
+				keep(nested)
+			}
+		}
+	}
+	for _, term := range terms {
+		for st := range structs {
+			if types.Identical(st, term) {
+				keep(st)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package selection
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_TypeSetStructs(t *testing.T) {
+	const src = `package a
+
+type point interface {
+	~struct{ x, y int } | ~struct{ z struct{ w int } }
+}
+
+type pt struct{ x, y int }
+
+type deep struct{ z struct{ w int } }
+
+type other struct{ x, y int64 }
+
+func sum[P point](p P) int { return 0 }
+
+func implicit[P ~struct{ name string }](p P) {}
+
+type named struct{ name string }
+
+type notConstraint struct{ x, y int }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+	}
+	tpkg, err := (&types.Config{}).Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{Fset: fset, Types: tpkg, TypesInfo: info, Syntax: []*ast.File{f}}
+	var got []string
+	for _, pos := range slices.Sorted(maps.Keys(TypeSetStructs(pkg))) {
+		got = append(got, fset.Position(pos).String())
+	}
+	want := []string{
+		"a.go:4:3",   // ~struct{ x, y int }
+		"a.go:4:25",  // ~struct{ z struct{ w int } }
+		"a.go:4:35",  // struct{ w int }
+		"a.go:7:9",   // pt
+		"a.go:9:11",  // deep
+		"a.go:9:21",  // struct{ w int } of deep
+		"a.go:15:18", // ~struct{ name string }
+		"a.go:17:12", // named
+		"a.go:19:20", // notConstraint, identical to a term
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,225 @@
+package renamer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// structFields returns a map from the names of struct fields to the field lists declaring them.
+// Struct types for which fixed returns true are left as is and their fields
+// are not in the result.
+func structFields(files []*ast.File, fixed func(*ast.StructType) bool) map[*ast.Ident]*ast.FieldList {
+	result := make(map[*ast.Ident]*ast.FieldList)
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			st, ok := node.(*ast.StructType)
+			if !ok || st.Fields == nil || fixed(st) {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				for _, name := range field.Names {
+					result[name] = st.Fields
+				}
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// splitField returns the field of list declaring name. A field declaring more than
+// one names, such as
+//
+//	A, B, C int
+//
+// is split around name, so name has its own field and can have its own tag:
+//
+//	A int
+//	B int
+//	C int
+//
+// Each of the split fields has its own copy of the type expression,
+// recorded in info as the original one. A field with a json key, whose
+// tag is not changed by renaming, is not split.
+func splitField(list *ast.FieldList, name *ast.Ident, info *types.Info) *ast.Field {
+	for i, field := range list.List {
+		j := slices.Index(field.Names, name)
+		if j < 0 {
+			continue
+		}
+		if len(field.Names) == 1 || hasJSONKey(field) {
+			return field
+		}
+		var split []*ast.Field
+		for _, names := range [][]*ast.Ident{field.Names[:j], field.Names[j : j+1], field.Names[j+1:]} {
+			if len(names) == 0 {
+				continue
+			}
+			f := &ast.Field{Names: names, Type: field.Type}
+			if len(split) > 0 {
+				f.Type = cloneExpr(field.Type, info)
+			}
+			if field.Tag != nil {
+				tag := *field.Tag
+				f.Tag = &tag
+			}
+			split = append(split, f)
+		}
+		split[0].Doc = field.Doc
+		split[len(split)-1].Comment = field.Comment
+		list.List = slices.Replace(list.List, i, i+1, split...)
+		return split[slices.IndexFunc(split, func(f *ast.Field) bool { return f.Names[0] == name })]
+	}
+	return nil
+}
+
+// cloneExpr returns a deep copy of expr. The types and uses of the copied
+// expressions and identifiers are recorded in info as the original ones.
+// Objects and scopes of the deprecated resolution are shared.
+func cloneExpr(expr ast.Expr, info *types.Info) ast.Expr {
+	return cloneValue(reflect.ValueOf(&expr).Elem(), info).Interface().(ast.Expr)
+}
+
+var (
+	objectType = reflect.TypeFor[*ast.Object]()
+	scopeType  = reflect.TypeFor[*ast.Scope]()
+)
+
+func cloneValue(v reflect.Value, info *types.Info) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		result := reflect.New(v.Type()).Elem()
+		result.Set(cloneValue(v.Elem(), info))
+		return result
+	case reflect.Pointer:
+		if v.IsNil() || v.Type() == objectType || v.Type() == scopeType {
+			return v
+		}
+		result := reflect.New(v.Type().Elem())
+		result.Elem().Set(cloneValue(v.Elem(), info))
+		if expr, ok := v.Interface().(ast.Expr); ok && info != nil {
+			clone := result.Interface().(ast.Expr)
+			if tv, ok := info.Types[expr]; ok {
+				info.Types[clone] = tv
+			}
+			if id, ok := expr.(*ast.Ident); ok {
+				if obj := info.Uses[id]; obj != nil {
+					info.Uses[clone.(*ast.Ident)] = obj
+				}
+			}
+		}
+		return result
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		result := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			result.Index(i).Set(cloneValue(v.Index(i), info))
+		}
+		return result
+	case reflect.Struct:
+		result := reflect.New(v.Type()).Elem()
+		for i := range v.NumField() {
+			result.Field(i).Set(cloneValue(v.Field(i), info))
+		}
+		return result
+	}
+	return v
+}
+
+// fieldTag returns the unquoted tag of field.
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+// hasJSONKey returns whether field has a json tag with a key, such as `json:"name,omitempty"`,
+// which is not changed by renaming the field. The key of `json:",omitempty"` is the field name.
+func hasJSONKey(field *ast.Field) bool {
+	value, _ := fieldTag(field).Lookup("json")
+	key, _, _ := strings.Cut(value, ",")
+	return key != ""
+}
+
+// addJSONTag adds `json:"name"` to the tag of field if it has no json key, or sets the
+// key of the json tag without one, such as `json:",omitempty"`, to name. Name is the
+// original name of the field, which was the default json key before the field is renamed.
+func addJSONTag(field *ast.Field, name string) {
+	if hasJSONKey(field) {
+		return
+	}
+	var tag string
+	if value, ok := fieldTag(field).Lookup("json"); ok {
+		tag = setTagValue(string(fieldTag(field)), "json", name+value)
+	} else {
+		tag = strings.TrimSpace(string(fieldTag(field)) + ` json:"` + name + `"`)
+	}
+	var value string
+	if strings.Contains(tag, "`") {
+		value = strconv.Quote(tag)
+	} else {
+		value = "`" + tag + "`"
+	}
+	if field.Tag == nil {
+		field.Tag = &ast.BasicLit{ValuePos: field.Type.End(), Kind: token.STRING}
+	}
+	field.Tag.Value = value
+}
+
+// setTagValue returns tag with the value of the first key replaced with value.
+// Tag is parsed as [reflect.StructTag.Lookup] does, and is returned as is if key is not found.
+func setTagValue(tag, key, value string) string {
+	for rest := tag; rest != ""; {
+		// Skip leading space.
+		i := 0
+		for i < len(rest) && rest[i] == ' ' {
+			i++
+		}
+		rest = rest[i:]
+		if rest == "" {
+			break
+		}
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(rest) && rest[i] > ' ' && rest[i] != ':' && rest[i] != '"' && rest[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(rest) || rest[i] != ':' || rest[i+1] != '"' {
+			break
+		}
+		name := rest[:i]
+		rest = rest[i+1:]
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(rest) && rest[i] != '"' {
+			if rest[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(rest) {
+			break
+		}
+		if name == key {
+			start := len(tag) - len(rest)
+			return tag[:start] + strconv.Quote(value) + tag[start+i+1:]
+		}
+		rest = rest[i+1:]
+	}
+	return tag
+}
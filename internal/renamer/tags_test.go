@@ -0,0 +1,79 @@
+package renamer
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func Test_addJSONTag(t *testing.T) {
+	const src = `package a
+
+type T struct {
+	A    int
+	B, C string
+	d, e int
+	F    int ` + "`xml:\"f\"`" + `
+	G    int ` + "`json:\"g\"`" + `
+	H    int "quoted:\"` + "`" + `\""
+	I    int ` + "`json:\",omitempty\"`" + `
+	J    int ` + "`xml:\"j\" json:\",string\" yaml:\"j\"`" + `
+	K, L int ` + "`json:\",omitempty\"`" + `
+	M    int ` + "`json:\"-\"`" + `
+	N, O, P []int
+	Q, R int
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := structFields([]*ast.File{f}, func(*ast.StructType) bool { return false })
+	for id, list := range fields {
+		// O, Q and R are not renamed.
+		if id.IsExported() && id.Name != "O" && id.Name != "Q" && id.Name != "R" {
+			addJSONTag(splitField(list, id, nil), id.Name)
+		}
+	}
+
+	var got strings.Builder
+	if err := format.Node(&got, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	const want = `package a
+
+type T struct {
+	A    int    ` + "`json:\"A\"`" + `
+	B    string ` + "`json:\"B\"`" + `
+	C    string ` + "`json:\"C\"`" + `
+	d, e int
+	F    int   ` + "`xml:\"f\" json:\"F\"`" + `
+	G    int   ` + "`json:\"g\"`" + `
+	H    int   "quoted:\"` + "`" + `\" json:\"H\""
+	I    int   ` + "`json:\"I,omitempty\"`" + `
+	J    int   ` + "`xml:\"j\" json:\"J,string\" yaml:\"j\"`" + `
+	K    int   ` + "`json:\"K,omitempty\"`" + `
+	L    int   ` + "`json:\"L,omitempty\"`" + `
+	M    int   ` + "`json:\"-\"`" + `
+	N    []int ` + "`json:\"N\"`" + `
+	O    []int
+	P    []int ` + "`json:\"P\"`" + `
+	Q, R int
+}
+`
+	if got.String() != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, got.String())
+	}
+	// The split fields do not share the type expression.
+	types := make(map[ast.Expr]bool)
+	for _, field := range f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List {
+		if types[field.Type] {
+			t.Errorf("type of %v is shared", field.Names[0])
+		}
+		types[field.Type] = true
+	}
+}
@@ -0,0 +1,69 @@
+package generic
+
+import "iter"
+
+// Parameterized aliases.
+type set[T comparable] = map[T]struct{}
+
+type pair[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+type pairs[K comparable, V any] = []pair[K, V]
+
+type list[T any] []T
+
+type listAlias[T any] = list[T]
+
+type sequence[T any] interface {
+	all() iter.Seq[T]
+}
+
+func (l list[T]) all() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, elem := range l {
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+func keys[K comparable](s set[K]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range s {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func entries[K comparable, V any](ps pairs[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, p := range ps {
+			if !yield(p.key, p.value) {
+				return
+			}
+		}
+	}
+}
+
+func total[T ~int](seq sequence[T]) (result T) {
+	for elem := range seq.all() {
+		result += elem
+	}
+	return
+}
+
+func Sum() int {
+	result := total[int](listAlias[int]{1, 2, 3})
+	for k := range keys(set[int]{4: {}}) {
+		result += k
+	}
+	for key, value := range entries(pairs[string, int]{{"a", 5}}) {
+		result += len(key) + value
+	}
+	return result
+}
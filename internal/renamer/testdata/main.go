@@ -0,0 +1,13 @@
+package main
+
+type command struct{}
+
+// main is a method, not the entry point of the program.
+func (command) main() {}
+
+func helper() string { return "helper" }
+
+func main() {
+	command{}.main()
+	println(helper())
+}
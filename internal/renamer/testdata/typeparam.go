@@ -0,0 +1,75 @@
+package typeparam
+
+// Methods selected through constraints.
+
+type named interface {
+	name() string
+}
+
+type inner struct{ id string }
+
+func (i inner) name() string { return i.id }
+
+// outer has name promoted from inner.
+type outer struct {
+	inner
+	size int
+}
+
+type pointer struct{ label string }
+
+func (p *pointer) name() string { return p.label }
+
+func nameOf[T named](v T) string {
+	return v.name()
+}
+
+func namesOf[T interface{ *E }, E named](v T) string {
+	return (*v).name()
+}
+
+// Constraint with both a method and a type set.
+type sizedName interface {
+	~struct {
+		inner
+		size int
+	}
+	name() string
+}
+
+func sizeOf[T sizedName](v T) int {
+	return len(v.name()) + struct {
+		inner
+		size int
+	}(v).size
+}
+
+// Core type struct constraints. Field names are part of struct identity.
+type point interface {
+	~struct{ x, y int }
+}
+
+// get is declared before the fields of pt, so it is renamed first.
+func (p pt) get() int { return p.x }
+
+type pt struct{ x, y int }
+
+func sum[P point](p P) int {
+	s := struct{ x, y int }(p)
+	return s.x + s.y
+}
+
+// Generic types selecting through type parameters.
+type box[T named] struct {
+	value T
+}
+
+func (b box[T]) label() string {
+	return b.value.name()
+}
+
+func use() (int, string, string) {
+	o := outer{inner{"a"}, 1}
+	b := box[outer]{value: o}
+	return sum(pt{1, 2}) + sizeOf(o) + pt{}.get(), nameOf(o) + namesOf(&o) + nameOf(&pointer{"b"}), b.label()
+}
@@ -0,0 +1,93 @@
+// Package report collects the findings of a run, which are written to the
+// file specified by the -report flag.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"runtime/trace"
+	"time"
+
+	"github.com/mkch/goingbad/schema"
+)
+
+// Hotspot is a piece of code which resists obfuscation.
+type Hotspot = schema.Hotspot
+
+// Kinds of hotspots.
+const (
+	UnsafePointer = schema.UnsafePointerHotspot
+	TypeSet       = schema.TypeSetHotspot
+	Assembly      = schema.AssemblyHotspot
+)
+
+// Pass is the time spent in a pass of a run.
+type Pass = schema.Pass
+
+// PartialPackage is a package with errors, which is partially obfuscated.
+type PartialPackage = schema.PartialPackage
+
+// PanickedPackage is a package in which internal errors are recovered.
+type PanickedPackage = schema.PanickedPackage
+
+// RecoveredPanic is an internal error recovered while renaming a declaration.
+type RecoveredPanic = schema.RecoveredPanic
+
+// QuarantinedPackage is a package copied verbatim instead of obfuscated.
+type QuarantinedPackage = schema.QuarantinedPackage
+
+// QuarantineReason is a detector which quarantined a package.
+type QuarantineReason = schema.QuarantineReason
+
+// Report is the findings of a run.
+type Report schema.Report
+
+// AddPartial adds a partially obfuscated package to r.
+func (r *Report) AddPartial(p PartialPackage) {
+	r.Partial = append(r.Partial, p)
+}
+
+// AddPanicked adds a package in which internal errors are recovered to r.
+func (r *Report) AddPanicked(p PanickedPackage) {
+	r.Panicked = append(r.Panicked, p)
+}
+
+// AddQuarantined adds a quarantined package to r.
+func (r *Report) AddQuarantined(p QuarantinedPackage) {
+	r.Quarantined = append(r.Quarantined, p)
+}
+
+// Begin starts pass in a runtime/trace region. The returned function ends the region
+// and adds the time elapsed to the duration of pass, so a pass run once for each
+// package is reported once with the total time.
+func (r *Report) Begin(ctx context.Context, pass string) (end func()) {
+	region := trace.StartRegion(ctx, pass)
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		region.End()
+		for i := range r.Passes {
+			if r.Passes[i].Name == pass {
+				r.Passes[i].Duration += elapsed
+				return
+			}
+		}
+		r.Passes = append(r.Passes, Pass{Name: pass, Duration: elapsed})
+	}
+}
+
+// AddHotspot adds a hotspot to r.
+func (r *Report) AddHotspot(h Hotspot) {
+	r.Hotspots = append(r.Hotspots, h)
+}
+
+// Save writes r to file in JSON.
+func (r *Report) Save(file string) error {
+	r.Header = schema.NewHeader(schema.ReportKind)
+	data, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0666)
+}
@@ -0,0 +1,28 @@
+package report
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_Begin(t *testing.T) {
+	var r Report
+	ctx := context.Background()
+	for _, pass := range []string{"a", "b", "a"} {
+		end := r.Begin(ctx, pass)
+		time.Sleep(time.Millisecond)
+		end()
+	}
+	var names []string
+	for _, pass := range r.Passes {
+		names = append(names, pass.Name)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(names, want) {
+		t.Fatalf("want %v, got %v", want, names)
+	}
+	if r.Passes[0].Duration < 2*time.Millisecond {
+		t.Fatalf("want total duration of pass a >= 2ms, got %v", r.Passes[0].Duration)
+	}
+}
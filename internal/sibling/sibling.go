@@ -0,0 +1,185 @@
+// Package sibling renames the platform-specific go files excluded from the build,
+// such as a_windows.go when loaded on linux, consistently with their included
+// siblings, such as a_linux.go, so the shared callers compile on all platforms.
+//
+// Excluded files are not type checked. Identifiers are resolved by the parser:
+// the identifiers not declared in local scopes refer to the package level
+// declarations, and the selected fields and methods are matched by names.
+package sibling
+
+import (
+	"cmp"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+
+	"github.com/mkch/gg"
+)
+
+// Names is the new names of the identifiers renamed in the included files of a package,
+// by their original names.
+type Names struct {
+	Package   map[string]string // Package level declarations.
+	Selectors map[string]string // Fields and methods.
+	// Ambiguous is the original names of the fields and methods renamed to different names,
+	// which can not be matched by name.
+	Ambiguous gg.Set[string]
+	// Imports is the new names of the exported declarations of imported packages, by import paths.
+	Imports map[string]map[string]string
+}
+
+// AddSelector adds a field or method renamed from name to newName.
+func (n *Names) AddSelector(name, newName string) {
+	if n.Ambiguous.Contains(name) {
+		return
+	}
+	if prev, ok := n.Selectors[name]; ok && prev != newName {
+		delete(n.Selectors, name)
+		n.Ambiguous.Add(name)
+		return
+	}
+	n.Selectors[name] = newName
+}
+
+// NewNames returns empty Names.
+func NewNames() *Names {
+	return &Names{
+		Package:   make(map[string]string),
+		Selectors: make(map[string]string),
+		Ambiguous: make(gg.Set[string]),
+		Imports:   make(map[string]map[string]string),
+	}
+}
+
+// Problem is an identifier which may not be renamed correctly.
+type Problem struct {
+	Pos     token.Pos
+	Name    string
+	Message string
+}
+
+// Rename renames the identifiers of f with names. Package names of the imports of f
+// are resolved by importName, which returns the name of the package of import path.
+// The identifiers which may not be renamed correctly are returned as problems.
+func Rename(f *ast.File, names *Names, importName func(path string) string) (problems []Problem) {
+	// Names of the imported packages in f, to their paths.
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := importName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+
+	selector := func(id *ast.Ident) {
+		if newName, ok := names.Selectors[id.Name]; ok {
+			id.Name = newName
+		} else if names.Ambiguous.Contains(id.Name) {
+			problems = append(problems, Problem{id.Pos(), id.Name, "field or method renamed to different names"})
+		}
+	}
+	// Identifiers handled as selectors, or never renamed.
+	done := make(gg.Set[*ast.Ident])
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ImportSpec:
+			return false
+		case *ast.SelectorExpr:
+			if x, ok := node.X.(*ast.Ident); ok && x.Obj == nil {
+				if path, ok := imports[x.Name]; ok {
+					// Qualified identifier.
+					if newName, ok := names.Imports[path][node.Sel.Name]; ok {
+						node.Sel.Name = newName
+					}
+					done.Add(x)
+					done.Add(node.Sel)
+					return false
+				}
+			}
+			selector(node.Sel)
+			done.Add(node.Sel)
+		case *ast.FuncDecl:
+			if node.Recv != nil {
+				selector(node.Name)
+				done.Add(node.Name)
+			}
+		case *ast.StructType:
+			for _, field := range node.Fields.List {
+				for _, name := range field.Names {
+					selector(name)
+					done.Add(name)
+				}
+			}
+		case *ast.InterfaceType:
+			for _, method := range node.Methods.List {
+				for _, name := range method.Names {
+					selector(name)
+					done.Add(name)
+				}
+			}
+		case *ast.CompositeLit:
+			// The keys of struct literals are fields, and the keys of the others are expressions.
+			// Without types, the keys not of map, slice or array literals are fields if they
+			// are renamed fields, even if they are resolved to local declarations.
+			switch node.Type.(type) {
+			case *ast.MapType, *ast.ArrayType:
+				return true
+			}
+			for _, elt := range node.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kv.Key.(*ast.Ident); ok {
+					if _, isField := names.Selectors[key.Name]; isField || names.Ambiguous.Contains(key.Name) {
+						selector(key)
+						done.Add(key)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	// Names declared in local scopes, which may shadow the new names of package level declarations.
+	local := make(map[string]token.Pos)
+	ast.Inspect(f, func(node ast.Node) bool {
+		id, ok := node.(*ast.Ident)
+		if !ok || done.Contains(id) || id.Name == "_" {
+			return true
+		}
+		if id.Obj != nil && f.Scope.Lookup(id.Name) != id.Obj {
+			if id.Obj.Pos() == id.Pos() && id.Obj.Kind != ast.Lbl {
+				local[id.Name] = id.Pos()
+			}
+			return true // Local.
+		}
+		if id == f.Name {
+			return true
+		}
+		if newName, ok := names.Package[id.Name]; ok {
+			id.Name = newName
+		}
+		return true
+	})
+	for _, newName := range names.Package {
+		if pos, ok := local[newName]; ok {
+			problems = append(problems, Problem{pos, newName, "local declaration may shadow the new name of a package level declaration"})
+		}
+	}
+	// Package level declarations only in the excluded files are not renamed.
+	newNames := make(gg.Set[string])
+	for _, newName := range names.Package {
+		newNames.Add(newName)
+	}
+	for name, obj := range f.Scope.Objects {
+		if _, renamed := names.Package[name]; !renamed && newNames.Contains(name) {
+			problems = append(problems, Problem{obj.Pos(), name, "declaration conflicts with the new name of a package level declaration"})
+		}
+	}
+	slices.SortFunc(problems, func(a, b Problem) int { return cmp.Compare(a.Pos, b.Pos) })
+	return
+}
@@ -0,0 +1,54 @@
+package sibling
+
+import (
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_Rename(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a_windows.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := NewNames()
+	// Renamed in a_linux.go.
+	names.Package["handle"] = "a"
+	names.Package["closeHandle"] = "e"
+	names.Package["errNone"] = "f"
+	names.Package["b"] = "b"                   // Unchanged, but shadowed by a local in windowsOnly.
+	names.Package["linuxOnly"] = "windowsOnly" // Conflicts with the declaration only in a_windows.go.
+	names.AddSelector("fd", "b")
+	names.AddSelector("name", "c")
+	names.AddSelector("close", "d")
+	names.AddSelector("size", "g")
+	names.AddSelector("size", "h") // Another size renamed differently.
+	names.Imports["example.com/lib"] = map[string]string{"Check": "A"}
+
+	problems := Rename(f, names, path.Base)
+	var got []string
+	for _, p := range problems {
+		got = append(got, fset.Position(p.Pos).String()+" "+p.Name)
+	}
+	if want := []string{"testdata/a_windows.go:24:6 windowsOnly", "testdata/a_windows.go:25:6 b", "testdata/a_windows.go:29:18 size"}; !slices.Equal(got, want) {
+		t.Errorf("want problems %v, got %v", want, got)
+	}
+
+	var out strings.Builder
+	if err := format.Node(&out, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/a_windows-renamed.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(want) {
+		t.Fatalf("want\n%v\ngot\n%v", string(want), out.String())
+	}
+}
@@ -0,0 +1,29 @@
+package a
+
+import (
+	"os"
+
+	lib "example.com/lib"
+)
+
+type a struct {
+	b uintptr
+	c string
+}
+
+func (h *a) d() error {
+	return e(h.b)
+}
+
+func e(fd uintptr) error {
+	h := a{b: fd, c: os.Args[0]}
+	_ = h.c
+	return lib.A(f)
+}
+
+func windowsOnly() {
+	var b int
+	_ = b
+}
+
+func (h *a) size() int { return 0 }
@@ -0,0 +1,29 @@
+package a
+
+import (
+	"os"
+
+	lib "example.com/lib"
+)
+
+type handle struct {
+	fd   uintptr
+	name string
+}
+
+func (h *handle) close() error {
+	return closeHandle(h.fd)
+}
+
+func closeHandle(fd uintptr) error {
+	h := handle{fd: fd, name: os.Args[0]}
+	_ = h.name
+	return lib.Check(errNone)
+}
+
+func windowsOnly() {
+	var b int
+	_ = b
+}
+
+func (h *handle) size() int { return 0 }
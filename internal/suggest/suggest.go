@@ -0,0 +1,73 @@
+// Package suggest finds the names similar to misspelled ones.
+package suggest
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Distance returns the optimal string alignment distance between a and b, counted in runes:
+// the number of insertions, deletions, substitutions and transpositions of adjacent runes
+// to change a into b, where no substring is edited more than once.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// MaxSuggestions is the maximum number of names returned by Similar.
+const MaxSuggestions = 3
+
+// Similar returns at most MaxSuggestions candidates similar to name, the most similar first.
+// A candidate is similar if, ignoring case, it is within a distance of a third of
+// the length of name, at least 1. Ties are broken by the distance with case.
+func Similar(name string, candidates iter.Seq[string]) []string {
+	type candidate struct {
+		name     string
+		distance int // Ignoring case.
+		exact    int // Distance with case.
+	}
+	limit := max(1, len([]rune(name))/3)
+	var similar []candidate
+	seen := make(map[string]bool)
+	for c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		d := Distance(strings.ToLower(name), strings.ToLower(c))
+		if d <= limit {
+			similar = append(similar, candidate{c, d, Distance(name, c)})
+		}
+	}
+	slices.SortFunc(similar, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.exact, b.exact), strings.Compare(a.name, b.name))
+	})
+	result := make([]string, 0, min(len(similar), MaxSuggestions))
+	for _, c := range similar[:min(len(similar), MaxSuggestions)] {
+		result = append(result, c.name)
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package suggest
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_Distance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"Name", "Name", 0},
+		{"Name", "Naem", 1},
+		{"ca", "abc", 3},
+		{"Name", "Names", 1},
+		{"kitten", "sitting", 3},
+		{"名字", "名子", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q): want %v, got %v", tt.a, tt.b, tt.want, got)
+		}
+		if got := Distance(tt.b, tt.a); got != tt.want {
+			t.Errorf("Distance(%q, %q): want %v, got %v", tt.b, tt.a, tt.want, got)
+		}
+	}
+}
+
+func Test_Similar(t *testing.T) {
+	candidates := []string{"Name", "Names", "name", "Game", "Value", "NewName", "Name"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Naem", []string{"Name", "name"}},
+		{"Nme", []string{"Name", "name"}},
+		{"NAME", []string{"Name", "name", "Game"}},
+		{"Name", []string{"name", "Game", "Names"}},
+		{"Unknown", []string{}},
+	}
+	for _, tt := range tests {
+		if got := Similar(tt.name, slices.Values(candidates)); !slices.Equal(got, tt.want) {
+			t.Errorf("Similar(%q): want %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package target
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// symbolDirective annotates the string constants holding the names of declarations
+// in the same package, such as the method names looked up by reflection:
+//
+//	//goingbad:symbol
+//	const handler = "Server.Handle"
+//
+// The value is either the name of a package-level declaration, or "T.M", where
+// M is a field or method of the package-level type T.
+const symbolDirective = "//goingbad:symbol"
+
+func init() {
+	Register(symbolDetector{})
+}
+
+type symbolDetector struct{}
+
+func (symbolDetector) Name() string {
+	return "symbol"
+}
+
+func hasSymbolDirective(doc *ast.CommentGroup) bool {
+	return doc != nil && slices.ContainsFunc(doc.List, func(c *ast.Comment) bool { return c.Text == symbolDirective })
+}
+
+func (symbolDetector) Detect(pkg *packages.Package) (result []Target) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if !hasSymbolDirective(decl.Doc) && !hasSymbolDirective(spec.Doc) {
+					continue
+				}
+				for _, value := range spec.Values {
+					if t, ok := symbolTarget(pkg, value); ok {
+						result = append(result, t)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// symbolTarget returns the target of the string literal value, which names a declaration in pkg.
+func symbolTarget(pkg *packages.Package, value ast.Expr) (t Target, ok bool) {
+	lit, ok := ast.Unparen(value).(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return
+	}
+	s := constant.StringVal(constant.MakeFromLiteral(lit.Value, lit.Kind, 0))
+	objs := lookupSymbol(pkg.Types, s)
+	if objs == nil {
+		slog.Warn("symbol not found", "pos", pkg.Fset.Position(lit.Pos()), "symbol", s)
+		return t, false
+	}
+	return Target{
+		Pos:     lit.Pos(),
+		Objects: objs,
+		Rewrite: func(names []string) {
+			lit.Value = strconv.Quote(strings.Join(names, "."))
+		},
+	}, true
+}
+
+// lookupSymbol returns the objects named by symbol in pkg, "Name" or "Type.Member".
+// Nil is returned if not found.
+func lookupSymbol(pkg *types.Package, symbol string) []types.Object {
+	typeName, member, qualified := strings.Cut(symbol, ".")
+	obj := pkg.Scope().Lookup(typeName)
+	if obj == nil {
+		return nil
+	}
+	if !qualified {
+		return []types.Object{obj}
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil
+	}
+	sel, _, _ := types.LookupFieldOrMethod(obj.Type(), true, pkg, member)
+	if sel == nil {
+		return nil
+	}
+	return []types.Object{obj, sel}
+}
@@ -0,0 +1,77 @@
+// Package target provides the extension point for the references to symbols
+// outside identifiers, such as the names of declarations in string literals,
+// struct tags or templates. These rename targets are rewritten with the same
+// names as the declarations they refer to, so they remain valid after renaming.
+package target
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Target is a reference to symbols outside identifiers.
+type Target struct {
+	Pos     token.Pos      // The position of the reference.
+	Objects []types.Object // The objects referred to, such as a type and its method.
+	// Rewrite rewrites the reference with the names of Objects after renaming,
+	// in the same order as Objects. It is called only if some of the names are changed.
+	Rewrite func(names []string)
+}
+
+// Detector detects the rename targets in packages.
+type Detector interface {
+	// Name is the name of the detector used in logs.
+	Name() string
+	// Detect returns the rename targets in pkg.
+	// It is called before any identifier of pkg is renamed.
+	Detect(pkg *packages.Package) []Target
+}
+
+var detectors []Detector
+
+// Register registers d. It is typically called in init functions.
+// The detectors run in the order they are registered.
+func Register(d Detector) {
+	detectors = append(detectors, d)
+}
+
+// Detect returns the rename targets in pkg found by all the registered detectors.
+func Detect(pkg *packages.Package) (result []Found) {
+	for _, d := range detectors {
+		for _, t := range d.Detect(pkg) {
+			result = append(result, Found{Target: t, Position: pkg.Fset.Position(t.Pos), Detector: d.Name()})
+		}
+	}
+	return
+}
+
+// Found is a Target with the name of the Detector which found it.
+type Found struct {
+	Target
+	Position token.Position
+	Detector string
+}
+
+// Rewrite rewrites t with the names of its objects in newNames,
+// which maps the renamed objects to their new names.
+// The objects not in newNames keep their names.
+// The new names are returned if t is rewritten, or nil if none of the objects is renamed.
+func Rewrite(t Target, newNames map[types.Object]string) []string {
+	names := make([]string, len(t.Objects))
+	changed := false
+	for i, obj := range t.Objects {
+		if name, ok := newNames[obj]; ok {
+			names[i] = name
+			changed = changed || name != obj.Name()
+		} else {
+			names[i] = obj.Name()
+		}
+	}
+	if !changed {
+		return nil
+	}
+	t.Rewrite(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package target
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_symbolDetector(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/a.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := symbolDetector{}.Detect(&packages.Package{Fset: fset, Syntax: []*ast.File{f}, Types: pkg, TypesInfo: info})
+
+	newNames := make(map[types.Object]string)
+	for id, obj := range info.Defs {
+		switch id.Name {
+		case "Server":
+			newNames[obj] = "a"
+		case "Handle":
+			newNames[obj] = "b"
+		case "Addr":
+			newNames[obj] = "Addr" // Not changed.
+		}
+	}
+	var got []string
+	for _, target := range targets {
+		if Rewrite(target, newNames) == nil {
+			continue
+		}
+		got = append(got, fset.Position(target.Pos).String())
+	}
+
+	var values []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok {
+			values = append(values, lit.Value)
+		}
+		return true
+	})
+	if want := []string{`"a.b"`, `"a.Addr"`, `"run"`, `"run"`, `"Server.Close"`}; !slices.Equal(values, want) {
+		t.Fatalf("want %v, got %v", want, values)
+	}
+	if want := []string{"testdata/a.go:12:17", "testdata/a.go:16:9"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
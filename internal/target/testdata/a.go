@@ -0,0 +1,24 @@
+package a
+
+type Server struct {
+	Addr string
+}
+
+func (s *Server) Handle() {}
+
+func run() {}
+
+//goingbad:symbol
+const handler = "Server.Handle"
+
+const (
+	//goingbad:symbol
+	addr = "Server.Addr"
+	name = "run"
+)
+
+//goingbad:symbol
+const entry = "run"
+
+//goingbad:symbol
+const missing = "Server.Close"
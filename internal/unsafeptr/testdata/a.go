@@ -0,0 +1,36 @@
+package a
+
+import "unsafe"
+
+type header struct {
+	data unsafe.Pointer
+	len  int
+}
+
+type inner struct {
+	a, b int
+}
+
+type outer struct {
+	inners [2]inner
+}
+
+type view struct {
+	x [4]int
+}
+
+type unrelated struct {
+	s string
+}
+
+func sliceData(s []int) unsafe.Pointer {
+	return (*header)(unsafe.Pointer(&s)).data
+}
+
+func reinterpret(o *outer) *view {
+	return (*view)(unsafe.Pointer(o))
+}
+
+func noConversion(u unrelated) unsafe.Pointer {
+	return unsafe.Pointer(&u)
+}
@@ -0,0 +1,100 @@
+// Package unsafeptr finds the conversions through unsafe.Pointer, which
+// reinterpret memory and thus depend on the layout of the types involved.
+package unsafeptr
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// Conversion is a conversion from unsafe.Pointer to a pointer type, such as
+//
+//	(*T)(unsafe.Pointer(p))
+type Conversion struct {
+	Pos  token.Pos
+	From types.Type // Type of p if the operand is a conversion to unsafe.Pointer, nil otherwise.
+	To   types.Type // *T
+}
+
+// isUnsafePointer returns whether t is unsafe.Pointer.
+func isUnsafePointer(t types.Type) bool {
+	basic, ok := types.Unalias(t).(*types.Basic)
+	return ok && basic.Kind() == types.UnsafePointer
+}
+
+// conversion returns the target type and operand of conversion expr.
+// ok is false if expr is not a conversion.
+func conversion(info *types.Info, expr ast.Expr) (target types.Type, operand ast.Expr, ok bool) {
+	call, isCall := ast.Unparen(expr).(*ast.CallExpr)
+	if !isCall || len(call.Args) != 1 {
+		return
+	}
+	if tv, found := info.Types[call.Fun]; !found || !tv.IsType() {
+		return
+	}
+	return info.TypeOf(call.Fun), call.Args[0], true
+}
+
+// Find returns the conversions from unsafe.Pointer to pointer types in pkg.
+func Find(pkg *packages.Package) (result []Conversion) {
+	info := pkg.TypesInfo
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			expr, ok := node.(ast.Expr)
+			if !ok {
+				return true
+			}
+			to, operand, ok := conversion(info, expr)
+			if !ok {
+				return true
+			}
+			if _, ok := types.Unalias(to).Underlying().(*types.Pointer); !ok || !isUnsafePointer(info.TypeOf(operand)) {
+				return true
+			}
+			c := Conversion{Pos: expr.Pos(), To: to}
+			if target, from, ok := conversion(info, operand); ok && isUnsafePointer(target) {
+				c.From = info.TypeOf(from)
+			}
+			result = append(result, c)
+			return true
+		})
+	}
+	return
+}
+
+// Structs returns the struct types whose layout conversions depend on.
+// They are the struct types pointed to by the types converted from and to,
+// and the struct types of their fields and array elements, recursively.
+func Structs(conversions []Conversion) gg.Set[*types.Struct] {
+	result := make(gg.Set[*types.Struct])
+	var add func(t types.Type)
+	add = func(t types.Type) {
+		switch t := types.Unalias(t).Underlying().(type) {
+		case *types.Struct:
+			if result.Contains(t) {
+				return
+			}
+			result.Add(t)
+			for field := range t.Fields() {
+				add(field.Type())
+			}
+		case *types.Array:
+			add(t.Elem())
+		}
+	}
+	for _, c := range conversions {
+		for _, t := range []types.Type{c.From, c.To} {
+			if t == nil {
+				continue
+			}
+			if ptr, ok := types.Unalias(t).Underlying().(*types.Pointer); ok {
+				add(ptr.Elem())
+			}
+		}
+	}
+	return result
+}
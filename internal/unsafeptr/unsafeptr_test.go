@@ -0,0 +1,64 @@
+package unsafeptr
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Find(t *testing.T) {
+	pkg := loadPackage("testdata/a.go")
+	conversions := Find(pkg)
+	var got []string
+	for _, c := range conversions {
+		from := "<nil>"
+		if c.From != nil {
+			from = types.TypeString(c.From, types.RelativeTo(pkg.Types))
+		}
+		got = append(got, from+" -> "+types.TypeString(c.To, types.RelativeTo(pkg.Types)))
+	}
+	if want := []string{"*[]int -> *header", "*outer -> *view"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	structs := Structs(conversions)
+	for _, name := range []string{"header", "inner", "outer", "view", "unrelated"} {
+		st := pkg.Types.Scope().Lookup(name).Type().Underlying().(*types.Struct)
+		if want := name != "unrelated"; structs.Contains(st) != want {
+			t.Errorf("%v: want %v, got %v", name, want, !want)
+		}
+	}
+}
+
+func loadPackage(filename string) *packages.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := conf.Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &packages.Package{
+		Name:      pkg.Name(),
+		PkgPath:   pkg.Path(),
+		Fset:      fset,
+		Syntax:    []*ast.File{f},
+		Types:     pkg,
+		TypesInfo: info,
+	}
+}
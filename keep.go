@@ -0,0 +1,85 @@
+package main
+
+import (
+	"iter"
+	"maps"
+	"path"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/suggest"
+	"golang.org/x/tools/go/packages"
+)
+
+// unmatchedKeep is a name of -keep which matches no declaration.
+type unmatchedKeep struct {
+	Name        string   // The name as specified, pkg.Name or Name.
+	Suggestions []string // Similar names in the same format.
+}
+
+// unmatchedKeepNames returns the names, pkg.Name or Name if pkg is empty, matching no declaration
+// in pkgs, as -keep matches them, with the similar declared names as suggestions.
+func unmatchedKeepNames(pkgs []*packages.Package, names iter.Seq2[string, string]) (unmatched []unmatchedKeep) {
+	// Declared names by package paths.
+	declared := make(map[string]gg.Set[string])
+	all := make(gg.Set[string])
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		if declared[pkg.PkgPath] == nil {
+			declared[pkg.PkgPath] = make(gg.Set[string])
+		}
+		for id, obj := range pkg.TypesInfo.Defs {
+			if obj != nil && id.Name != "_" {
+				declared[pkg.PkgPath].Add(id.Name)
+				all.Add(id.Name)
+			}
+		}
+	}
+	for pkg, name := range names {
+		if pkg == "" {
+			if !all.Contains(name) {
+				unmatched = append(unmatched, unmatchedKeep{name, suggest.Similar(name, maps.Keys(all))})
+			}
+			continue
+		}
+		// Packages are matched by paths or last elements of paths.
+		candidates := make(gg.Set[string])
+		found, matched := false, false
+		for pkgPath, pkgNames := range declared {
+			if pkgPath != pkg && path.Base(pkgPath) != pkg {
+				continue
+			}
+			found = true
+			if pkgNames.Contains(name) {
+				matched = true
+				break
+			}
+			for n := range pkgNames {
+				candidates.Add(n)
+			}
+		}
+		if matched {
+			continue
+		}
+		var suggestions []string
+		if found {
+			for _, similar := range suggest.Similar(name, maps.Keys(candidates)) {
+				suggestions = append(suggestions, pkg+"."+similar)
+			}
+		} else {
+			// Misspelled package.
+			for pkgPath, pkgNames := range declared {
+				if pkgNames.Contains(name) {
+					candidates.Add(pkgPath)
+					candidates.Add(path.Base(pkgPath))
+				}
+			}
+			for _, similar := range suggest.Similar(pkg, maps.Keys(candidates)) {
+				suggestions = append(suggestions, similar+"."+name)
+			}
+		}
+		unmatched = append(unmatched, unmatchedKeep{pkg + "." + name, suggestions})
+	}
+	return
+}
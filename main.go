@@ -1,36 +1,101 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"fmt"
-	"go/format"
+	"go/ast"
+	"go/parser"
 	"go/token"
+	"go/types"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime/trace"
 	"slices"
 	"strings"
+	"text/template"
 
 	"flag"
 
 	"github.com/mkch/gg"
-	filepath2 "github.com/mkch/gg/filepath"
 	"github.com/mkch/gg/os2"
+	"github.com/mkch/goingbad/internal/annotate"
+	"github.com/mkch/goingbad/internal/asmstub"
+	"github.com/mkch/goingbad/internal/cache"
 	"github.com/mkch/goingbad/internal/comments"
+	"github.com/mkch/goingbad/internal/eol"
+	"github.com/mkch/goingbad/internal/filename"
 	"github.com/mkch/goingbad/internal/flags"
+	"github.com/mkch/goingbad/internal/generated"
+	"github.com/mkch/goingbad/internal/hostfunc"
 	"github.com/mkch/goingbad/internal/idgen"
+	"github.com/mkch/goingbad/internal/invariant"
+	"github.com/mkch/goingbad/internal/mapping"
+	"github.com/mkch/goingbad/internal/mocks"
+	"github.com/mkch/goingbad/internal/nametable"
+	"github.com/mkch/goingbad/internal/partial"
+	"github.com/mkch/goingbad/internal/pattern"
+	"github.com/mkch/goingbad/internal/protobuf"
+	"github.com/mkch/goingbad/internal/prune"
 	"github.com/mkch/goingbad/internal/renamer"
+	"github.com/mkch/goingbad/internal/renamer/selection"
+	"github.com/mkch/goingbad/internal/report"
+	"github.com/mkch/goingbad/internal/sibling"
+	"github.com/mkch/goingbad/internal/target"
+	"github.com/mkch/goingbad/internal/unsafeptr"
+	"github.com/mkch/goingbad/schema"
+	"github.com/mkch/goingbad/sigcompat"
+	"github.com/mkch/iter2"
 	"golang.org/x/tools/go/packages"
 )
 
+// Exit codes of the command. Scripts wrapping the command depend on them,
+// see the "Exit status" section of usage.txt.
+const (
+	exitOK       = 0
+	exitUsage    = 1 // Invalid command line.
+	exitFailure  = 2 // Obfuscation or subcommand failed.
+	exitMismatch = 3 // Output files do not match the manifest, see verify.
+)
+
+// usageError is an invalid command line found after the flags are parsed.
+type usageError struct{ error }
+
+func (err usageError) Unwrap() error { return err.error }
+
 var cmdArgs *flags.Flags
 var idGenerator *idgen.Generator
 
+// written is the output files written, for -manifest.
+var written []string
+
+// dryRun is set while the output files are recorded in written without being written,
+// to find the existing files which would be overwritten.
+var dryRun bool
+
 func main() {
-	cmdArgs = flags.Init()
+	if len(os.Args) > 1 && os.Args[1] == "mapdiff" {
+		os.Exit(mapDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(verify(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "unmap" {
+		os.Exit(unmap(os.Args[2:]))
+	}
+
+	var err error
+	if cmdArgs, err = flags.Init(); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage) // Reported by the flag package.
+	}
 	logLevel := slog.LevelError
 	if cmdArgs.Debug {
 		logLevel = slog.LevelDebug
@@ -41,36 +106,73 @@ func main() {
 
 	slog.Debug("debug mode")
 
-	if cmdArgs.OutDir == "" {
+	if cmdArgs.OutDir == "" && !cmdArgs.Plan {
 		slog.Error("required flag -out-dir is missing")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
-	var args []string
-	if args = flag.Args(); len(args) == 0 {
+	args := flag.Args()
+	if cmdArgs.PkgFile != "" {
+		patterns, err := pattern.ReadFile(cmdArgs.PkgFile)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitUsage)
+		}
+		args = append(args, patterns...)
+		if n := pattern.Len(args); n > pattern.MaxLen {
+			slog.Error("patterns are too long for the command line of the go command, use patterns with ... and -exclude instead", "length", n, "max", pattern.MaxLen)
+			os.Exit(exitUsage)
+		}
+	}
+	if len(args) == 0 {
 		args = []string{"."}
 	}
 
-	cmdArgs.KeepNames.Set("main.main")
+	if cmdArgs.IncludeTests {
+		slog.Info("test code will be included")
+	}
+
+	run := obfuscate
+	if cmdArgs.Plan {
+		run = printPlan
+	}
+	if err := run(args); err != nil {
+		slog.Error(err.Error())
+		if errors.As(err, new(usageError)) {
+			os.Exit(exitUsage)
+		}
+		os.Exit(exitFailure)
+	}
+	slog.Info("done.")
+}
+
+// obfuscate obfuscates the packages matching patterns as specified by cmdArgs.
+func obfuscate(patterns []string) (err error) {
 	if len(cmdArgs.Seeds) == 0 {
 		slog.Info("no seeds, use default.")
 		cmdArgs.Seeds.Set("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789")
 	}
-
-	if cmdArgs.IncludeTests {
-		slog.Info("test code will be included")
+	if idGenerator, err = createIDGenerator(); err != nil {
+		return
 	}
+	return traced(func() error { return rename(patterns...) })
+}
 
-	var err error
-	idGenerator, err = createIDGenerator()
-	if err == nil {
-		err = rename(args...)
+// traced runs f with the execution trace written to the file specified by -trace.
+func traced(f func() error) (err error) {
+	if cmdArgs.TraceFile == "" {
+		return f()
 	}
+	w, err := os.Create(cmdArgs.TraceFile)
 	if err != nil {
-		slog.Error(err.Error())
-		os.Exit(2)
+		return
 	}
-	slog.Info("done.")
+	defer gg.ChainError(w.Close, &err)
+	if err = trace.Start(w); err != nil {
+		return
+	}
+	defer trace.Stop()
+	return f()
 }
 
 var reSpace = regexp.MustCompile(`\s+`)
@@ -82,9 +184,24 @@ func createIDGenerator() (*idgen.Generator, error) {
 		if err != nil {
 			return nil, err
 		}
-		seeds = append(seeds, reSpace.Split(string(contents), -1)...)
+		seeds = append(seeds, slices.DeleteFunc(reSpace.Split(string(contents), -1), func(seed string) bool { return seed == "" })...)
+	}
+	// Missing kinds of seeds are not replaced with defaults, which would generate
+	// names of characters the user did not choose.
+	unusable, err := idgen.Check(seeds...)
+	if len(unusable) > 0 {
+		slog.Warn("seeds not composed of letters and digits are not used", "seeds", strings.Join(unusable, " "))
 	}
-	return idgen.NewGenerator(seeds...), nil
+	// Exported names are generated only if exports are renamed, otherwise the default
+	// elements of missing upper case letters are never used.
+	if !cmdArgs.RenameInternalExports && !errors.Is(err, idgen.ErrNoUnexportedElement) {
+		err = nil
+	}
+	if err != nil {
+		return nil, usageError{fmt.Errorf("invalid seeds: %w", err)}
+	}
+	// Secure names are derived from the salt, so every run generates the same names.
+	return idgen.NewGenerator(seeds...).WithSecret(cmdArgs.Salt), nil
 }
 
 func internalPos(pkgPath string) int {
@@ -137,15 +254,77 @@ func logPackageErrors(pkgs []*packages.Package) int {
 func rename(pkgs ...string) (err error) {
 	const mode = packages.NeedTypes |
 		packages.NeedName |
+		packages.NeedFiles |
 		packages.NeedCompiledGoFiles |
 		packages.NeedSyntax |
 		packages.NeedTypesInfo |
 		packages.NeedModule |
 		packages.NeedEmbedFiles
 
+	ctx, task := trace.NewTask(context.Background(), "rename")
+	defer task.End()
+	// Report is saved even if renaming fails, for the timing of passes,
+	// but not if nothing is written because the output files exist.
+	var rep report.Report
+	if cmdArgs.ReportFile != "" {
+		defer func() {
+			if errors.As(err, new(conflictError)) {
+				return
+			}
+			slog.Info("writing report...\t", "path", cmdArgs.ReportFile)
+			err = errors.Join(err, rep.Save(cmdArgs.ReportFile))
+		}()
+	}
+
+	if cmdArgs.Annotate && cmdArgs.MapFile == "" {
+		return usageError{errors.New("-annotate requires -map")}
+	}
+	if cmdArgs.PreviousMap != "" && cmdArgs.MapFile == "" {
+		return usageError{errors.New("-previous-map requires -map")}
+	}
+	if cmdArgs.Interactive && cmdArgs.Force {
+		return usageError{errors.New("-i can not be used with -overwrite")}
+	}
+	// The output of these depends on the packages importing a package, which are not covered by
+	// the cache key of the package, or overwrites the inputs.
+	if cmdArgs.CacheDir != "" && (cmdArgs.Annotate || cmdArgs.Prune || cmdArgs.InPlace || cmdArgs.MessagesFile != "" || cmdArgs.MessagePackage != "") {
+		return usageError{errors.New("-cache can not be used with -annotate, -prune, -in-place, -messages or -message-package")}
+	}
+
+	// Read the keys first, not to fail after all the work.
+	var mapKey, tableKey []byte
+	if cmdArgs.MapKeyFile != "" {
+		if mapKey, err = mapping.ReadKey(cmdArgs.MapKeyFile); err != nil {
+			return
+		}
+	}
+	var lineage []schema.MappingRelease
+	if cmdArgs.PreviousMap != "" {
+		if lineage, err = mapping.LineageAfter(cmdArgs.PreviousMap, mapKey); err != nil {
+			return
+		}
+	}
+	if cmdArgs.MessagePackage != "" {
+		if name := filepath.Base(cmdArgs.MessagePackage); !nametable.IsValidPackageName(name) {
+			return usageError{fmt.Errorf("invalid package name of -message-package: %v", name)}
+		}
+	}
+	if cmdArgs.NameTable != "" {
+		if name := filepath.Base(cmdArgs.NameTable); !nametable.IsValidPackageName(name) {
+			return usageError{fmt.Errorf("invalid package name of -name-table: %v", name)}
+		}
+		if cmdArgs.NameTableKeyFile != "" {
+			if tableKey, err = mapping.ReadKey(cmdArgs.NameTableKeyFile); err != nil {
+				return
+			}
+		}
+	}
+
+	end := rep.Begin(ctx, "load")
 	loaded, err := packages.Load(&packages.Config{
-		Mode:  mode | gg.If(cmdArgs.IncludeTests, packages.NeedForTest, 0),
-		Tests: cmdArgs.IncludeTests}, pkgs...)
+		Mode:  mode | gg.If(loadTests(), packages.NeedForTest, 0),
+		Tests: loadTests()}, pkgs...)
+	end()
 	if err != nil {
 		return
 	}
@@ -153,109 +332,727 @@ func rename(pkgs ...string) (err error) {
 		return errors.New("no package loaded")
 	}
 	if n := logPackageErrors(loaded); n > 0 {
-		return fmt.Errorf("%d "+gg.If(n > 1, "errors", "error"), n)
+		if !cmdArgs.BestEffort {
+			return fmt.Errorf("%d "+gg.If(n > 1, "errors", "error"), n)
+		}
+		slog.Warn("packages with errors are partially obfuscated", "errors", n)
 	}
 
 	loaded = filterPackages(loaded)
+	if cmdArgs.Examples == flags.OmitExamples {
+		loaded = omitExamples(loaded)
+	}
+	if len(cmdArgs.Exclude) > 0 {
+		loaded = excludePackages(loaded)
+	}
+	if !cmdArgs.IncludeTests && cmdArgs.TestFiles == flags.OmitTests {
+		warnExcludedTests(loaded)
+	}
+	if unmatched := unmatchedKeepNames(loaded, cmdArgs.KeepNames.All()); len(unmatched) > 0 {
+		for _, u := range unmatched {
+			slog.Log(ctx, gg.If(cmdArgs.StrictKeep, slog.LevelError, slog.LevelWarn),
+				"name of -keep matches no declaration", "name", u.Name, "did you mean", strings.Join(u.Suggestions, ","))
+		}
+		if cmdArgs.StrictKeep {
+			return fmt.Errorf("%d "+gg.If(len(unmatched) > 1, "names", "name")+" of -keep "+gg.If(len(unmatched) > 1, "match", "matches")+" no declaration", len(unmatched))
+		}
+	}
 
-	var renamedExports map[token.Pos]string
+	// Properties that no transformation may change, checked before writing.
+	end = rep.Begin(ctx, "snapshot")
+	snapshot := make(invariant.Snapshot)
 	for _, pkg := range loaded {
-		renameExported := isInternalPackage(pkg.PkgPath) && cmdArgs.RenameInternalExports
-		if renameExported {
-			renamedExports = make(map[token.Pos]string)
+		snapshot.Take(pkg.Syntax...)
+	}
+	end()
+
+	// Interface methods implemented by generated mocks and the methods of the mocks
+	// must keep their names, or the mocks would no longer implement the interfaces.
+	end = rep.Begin(ctx, "mocks")
+	mockMethods := mocks.KeptMethods(loaded)
+	end()
+	for pkg, names := range mockMethods {
+		slog.Info("keeping methods of mocks and the interfaces they implement", "pkg", pkg, "methods", strings.Join(slices.Sorted(maps.Keys(names)), ","))
+	}
+
+	// Names that may be referenced by unresolved identifiers in packages with errors.
+	// Unresolved exported names may refer to the declarations of any package.
+	unresolved := make(map[string]gg.Set[string])
+	unresolvedExports := make(gg.Set[string])
+	for _, pkg := range loaded {
+		if !pkg.IllTyped {
+			continue
+		}
+		names := partial.UnresolvedNames(pkg)
+		unresolved[pkg.PkgPath] = names
+		for name := range names {
+			if ast.IsExported(name) {
+				unresolvedExports.Add(name)
+			}
 		}
-		renamer.Rename(pkg, idGenerator, renameExported, renamedExports, cmdArgs.KeepNames.Contains)
+		errs := slices.Collect(iter2.Map(slices.Values(pkg.Errors), packages.Error.Error))
+		if len(errs) == 0 {
+			errs = []string{"errors in dependencies"}
+		}
+		kept := slices.Sorted(maps.Keys(names))
+		slog.Warn("package is partially obfuscated", "pkg", pkg.PkgPath, "kept", strings.Join(kept, ","))
+		rep.AddPartial(report.PartialPackage{Package: pkg.PkgPath, Errors: errs, Kept: kept})
 	}
 
+	// Names of the methods and fields of the packages copied verbatim, which any
+	// package may depend on, such as by implementing their interfaces.
+	verbatimMembers := make(gg.Set[string])
+
+	keep := func(pkg, name string) bool {
+		return cmdArgs.KeepNames.Contains(pkg, name) || mockMethods[pkg].Contains(name) ||
+			unresolved[pkg].Contains(name) || unresolvedExports.Contains(name) || verbatimMembers.Contains(name)
+	}
+
+	// Struct types reinterpreted through unsafe.Pointer depend on their layout,
+	// which no pass may change.
+	end = rep.Begin(ctx, "unsafe")
+	fixedLayout := make(gg.Set[*types.Struct])
 	for _, pkg := range loaded {
-		renamer.RenameUsedExports(pkg, renamedExports)
+		conversions := unsafeptr.Find(pkg)
+		for _, c := range conversions {
+			position := pkg.Fset.Position(c.Pos)
+			slog.Warn("conversion through unsafe.Pointer depends on type layout", "pos", position, "to", c.To)
+			h := report.Hotspot{
+				Package:  pkg.PkgPath,
+				Position: position.String(),
+				Kind:     report.UnsafePointer,
+				Types:    []string{c.To.String()},
+				Message:  "conversion through unsafe.Pointer depends on type layout",
+			}
+			if c.From != nil {
+				h.Types = append([]string{c.From.String()}, h.Types...)
+			}
+			rep.AddHotspot(h)
+		}
+		maps.Copy(fixedLayout, unsafeptr.Structs(conversions))
 	}
+	end()
 
-	// write
+	// Fields of struct types in type sets are kept by the renamer.
+	end = rep.Begin(ctx, "type-sets")
 	for _, pkg := range loaded {
-		pkgDirRel := gg.Must(filepath.Rel(gg.Must(filepath.Abs("")), pkg.Dir))
-		destPkgDir := filepath.Join(cmdArgs.OutDir, pkgDirRel)
-		slog.Info("writing package...\t", "pkg", pkg.PkgPath, "dest", destPkgDir)
-		if err = os.MkdirAll(destPkgDir, 0777); err != nil {
+		structs := selection.TypeSetStructs(pkg)
+		for _, pos := range slices.Sorted(maps.Keys(structs)) {
+			position := pkg.Fset.Position(pos)
+			slog.Warn("fields of struct type in type set keep their names", "pos", position)
+			rep.AddHotspot(report.Hotspot{
+				Package:  pkg.PkgPath,
+				Position: position.String(),
+				Kind:     report.TypeSet,
+				Types:    []string{structs[pos].String()},
+				Message:  "fields of struct type in type set keep their names",
+			})
+		}
+	}
+	end()
+
+	// Functions implemented in assembly are referred to by their names, and so are their parameters.
+	end = rep.Begin(ctx, "asm-stubs")
+	asmStubs := make(map[*packages.Package][]asmstub.Stub)
+	for _, pkg := range loaded {
+		if asmStubs[pkg], err = asmstub.Find(pkg); err != nil {
+			end()
 			return
 		}
+		for _, stub := range asmStubs[pkg] {
+			slog.Info("keeping function implemented in assembly", "pos", stub.Pos, "name", stub.Name)
+			rep.AddHotspot(report.Hotspot{
+				Package:  pkg.PkgPath,
+				Position: stub.Pos.String(),
+				Kind:     report.Assembly,
+				Message:  "function implemented in assembly keeps its name",
+			})
+		}
+	}
+	end()
 
-		// go.mod and go.sum
-		if mod := pkg.Module.GoMod; mod != "" {
-			if pkg.Module.Dir == pkg.Dir {
-				dest := filepath.Join(destPkgDir, filepath.Base(pkg.Module.GoMod))
-				slog.Info("copying go.mod...\t", "from", pkg.Module.GoMod, "to", dest)
-				if err = os2.CopyFile(pkg.Module.GoMod, dest, cmdArgs.Force); err != nil {
-					return
+	// Packages copied verbatim instead of obfuscated: the quarantined packages,
+	// and the packages given up because of too many internal errors.
+	verbatim := make(gg.Set[*packages.Package])
+	if len(cmdArgs.Quarantine) > 0 {
+		end = rep.Begin(ctx, "quarantine")
+		for _, pkg := range loaded {
+			if reasons := quarantineReasons(pkg); len(reasons) > 0 {
+				slog.Warn("package is quarantined and copied verbatim", "pkg", pkg.PkgPath, "reasons", reasons)
+				rep.AddQuarantined(report.QuarantinedPackage{Package: pkg.PkgPath, Reasons: reasons})
+				verbatim.Add(pkg)
+				maps.Copy(verbatimMembers, memberNames(pkg))
+			}
+		}
+		end()
+	}
+
+	// References to symbols outside identifiers are detected before renaming,
+	// while the names still match the declarations.
+	end = rep.Begin(ctx, "targets")
+	var targets []target.Found
+	refs := make(map[*packages.Package][]string) // Paths of the packages of the objects referred to by targets.
+	for _, pkg := range loaded {
+		found := target.Detect(pkg)
+		for _, t := range found {
+			for _, obj := range t.Objects {
+				if obj.Pkg() != nil {
+					refs[pkg] = append(refs[pkg], obj.Pkg().Path())
+				}
+			}
+		}
+		targets = append(targets, found...)
+	}
+	end()
+
+	// Packages whose inputs are unchanged since the last run are copied from the cache.
+	var outputCache *cache.Cache
+	var cacheKeys map[*packages.Package]string
+	cached := make(map[*packages.Package]*cache.Entry)
+	if cmdArgs.CacheDir != "" {
+		end = rep.Begin(ctx, "cache")
+		kept := make(map[*packages.Package][]string)
+		for _, pkg := range loaded {
+			kept[pkg] = slices.Concat(slices.Collect(maps.Keys(mockMethods[pkg.PkgPath])), slices.Collect(maps.Keys(unresolved[pkg.PkgPath])))
+		}
+		shared := slices.Concat(slices.Collect(maps.Keys(unresolvedExports)), slices.Collect(maps.Keys(verbatimMembers)))
+		for st := range fixedLayout {
+			shared = append(shared, st.String())
+		}
+		outputCache, cacheKeys, cached, err = lookupCache(loaded, kept, refs, shared)
+		end()
+		if err != nil {
+			return
+		}
+		slog.Info("packages copied from cache", "cached", len(cached), "loaded", len(loaded))
+	}
+
+	rewriteTests := !cmdArgs.IncludeTests && cmdArgs.TestFiles == flags.RewriteTests
+	// Code generated by a previous run, such as the aliases of -compat-aliases, which keeps its names.
+	generatedCode := generated.NewRegistry(loaded)
+	newNames := make(map[types.Object]string)
+	secureObjects := make(gg.Set[types.Object]) // Renamed security-critical objects, which are not aliased.
+	renamedExports := make(map[token.Pos]string)
+	renames := mapping.Map{Salt: cmdArgs.Salt, Lineage: lineage}
+	// New names of the renamed declarations for the platform-specific files excluded from the build.
+	siblingNames := make(map[*packages.Package]*sibling.Names)
+	exportedNames := make(map[string]map[string]string) // New names of exported declarations by package paths.
+	// The keys of the map entries of the renamed definitions and objects, for -annotate.
+	type entryKey struct{ pkg, key string }
+	defKeys := make(map[*ast.Ident]entryKey)
+	objKeys := make(map[types.Object]entryKey)
+	signatures := sigcompat.NewCache()
+	pkgEntries := make(map[*packages.Package][]mapping.Entry) // Map entries of packages, for -cache.
+	var exportedIDGen *idgen.Generator
+	if cmdArgs.Salt != "" {
+		exportedIDGen = idGenerator.Salted(cmdArgs.Salt)
+	}
+	end = rep.Begin(ctx, "rename")
+	// A package given up is renamed before its importers, which keep its member names then.
+	for _, pkg := range dependencyOrder(loaded) {
+		if entry := cached[pkg]; entry != nil {
+			names := sibling.NewNames()
+			if err = restoreExports(pkg, entry, renamedExports, newNames, names, exportedNames); err != nil {
+				end()
+				return
+			}
+			siblingNames[pkg] = names
+			renames.Add(entry.Entries...)
+			continue
+		}
+		if verbatim.Contains(pkg) {
+			continue
+		}
+		renameExported := isInternalPackage(pkg.PkgPath) && cmdArgs.RenameInternalExports
+		var protobufIdents gg.Set[*ast.Ident]
+		if cmdArgs.Presets.Contains(flags.ProtobufPreset) {
+			// Internal fields and methods of generated messages are used by name by the protobuf runtime.
+			protobufIdents = protobuf.KeptIdents(pkg.Syntax)
+		}
+		// Functions bound to the host by directives are referred to by their names.
+		hostFuncs := hostfunc.KeptFuncs(pkg.Syntax)
+		stubIdents := asmstub.KeptIdents(pkg.Syntax, asmStubs[pkg])
+		keepDef := func(id *ast.Ident) bool {
+			// Identifiers declared in test files keep their names.
+			return rewriteTests && isTestFile(pkg.Fset.File(id.Pos()).Name()) ||
+				protobufIdents.Contains(id) || hostFuncs.Contains(id) || stubIdents.Contains(id) ||
+				generatedCode.Contains(id.Pos())
+		}
+		if cmdArgs.BlankUnused && !pkg.IllTyped {
+			for _, obj := range prune.BlankUnused(pkg, keepDef) {
+				slog.Info("blanked unused parameter", "pos", pkg.Fset.Position(obj.Pos()), "name", obj.Name())
+			}
+		}
+		result, panics, renameErr := renamer.Rename(pkg, &renamer.Options{
+			IDGen:          idGenerator,
+			ExportedIDGen:  exportedIDGen,
+			RenameExported: renameExported,
+			RenamedExports: renamedExports,
+			Keep:           keep,
+			KeepDef:        keepDef,
+			Secure:         cmdArgs.SecureNames.Contains,
+			JSONTags:       cmdArgs.JSONTags,
+			FixedLayout:    fixedLayout.Contains,
+			MaxPanics:      cmdArgs.MaxPanics,
+			Signatures:     signatures,
+		})
+		if len(panics) > 0 {
+			rep.AddPanicked(panickedPackage(pkg, panics, renameErr != nil))
+		}
+		if renameErr != nil {
+			slog.Error("too many internal errors, package is copied verbatim", "pkg", pkg.PkgPath, "panics", len(panics))
+			verbatim.Add(pkg)
+			maps.Copy(verbatimMembers, memberNames(pkg))
+			continue
+		}
+		names := sibling.NewNames()
+		for _, r := range result {
+			if r.Object != nil {
+				newNames[r.Object] = r.ID.Name
+				if r.Secure {
+					secureObjects.Add(r.Object)
 				}
-				sum := filepath2.ChangeExt(mod, ".sum")
-				if _, statErr := os.Stat(sum); statErr == nil {
-					dest = filepath.Join(destPkgDir, filepath.Base(sum))
-					slog.Info("copying go.sum...\t", "from", sum, "to", dest)
-					if err = os2.CopyFile(sum, dest, cmdArgs.Force); err != nil {
-						return
+				addSiblingName(pkg, names, exportedNames, r.Object, r.ID.Name)
+			}
+		}
+		siblingNames[pkg] = names
+		if cmdArgs.MapFile != "" || cmdArgs.NameTable != "" || outputCache != nil {
+			keyer := mapping.NewKeyer(pkg)
+			for _, r := range result {
+				entry := mapping.Entry{Package: pkg.PkgPath, Key: keyer.Key(r.ID, r.Object, r.OldName), Old: r.OldName, New: r.ID.Name}
+				renames.Add(entry)
+				pkgEntries[pkg] = append(pkgEntries[pkg], entry)
+				if cmdArgs.Annotate {
+					defKeys[r.ID] = entryKey{entry.Package, entry.Key}
+					if r.Object != nil {
+						objKeys[r.Object] = entryKey{entry.Package, entry.Key}
 					}
 				}
 			}
 		}
+	}
+
+	end()
+
+	end = rep.Begin(ctx, "rename-exports")
+	for _, pkg := range loaded {
+		renamer.RenameUsedExports(pkg, renamedExports)
+	}
+	end()
+
+	renames.Sort()
+	markers := make(map[entryKey]int)
+	if cmdArgs.Annotate {
+		for i, entry := range renames.Entries {
+			markers[entryKey{entry.Package, entry.Key}] = i
+		}
+	}
+
+	end = rep.Begin(ctx, "rewrite-targets")
+	for _, t := range targets {
+		if names := target.Rewrite(t.Target, newNames); names != nil {
+			slog.Info("rewrote reference to symbol", "pos", t.Position, "detector", t.Detector, "names", names)
+		}
+	}
+	end()
+
+	if cmdArgs.Prune {
+		end = rep.Begin(ctx, "prune")
+		for _, obj := range prune.UnusedExports(loaded) {
+			slog.Warn("exported declaration is not referenced in loaded packages", "pkg", obj.Pkg().Path(), "name", obj.Name())
+		}
+		for _, pkg := range loaded {
+			if pkg.IllTyped || verbatim.Contains(pkg) {
+				continue // Unresolved identifiers may reference any declaration.
+			}
+			var roots []*ast.File
+			if roots, err = pruneRoots(pkg); err != nil {
+				end()
+				return
+			}
+			for _, obj := range prune.Prune(pkg, roots) {
+				slog.Info("removed unreferenced declaration", "pkg", pkg.PkgPath, "name", obj.Name())
+			}
+		}
+		end()
+	}
+
+	// writePackage writes the output files of pkg. If dryRun is set, nothing is written,
+	// and only the paths of the files are recorded in written.
+	writePackage := func(pkg *packages.Package) (err error) {
+		destPkgDir := outDir(pkg.Dir)
+		firstWritten := len(written)
+		if entry := cached[pkg]; entry != nil {
+			return restoreFiles(entry)
+		}
+		if err = mkdirAll(destPkgDir); err != nil {
+			return
+		}
+
 		// go files
-		for i, f := range pkg.Syntax {
-			gofile := pkg.CompiledGoFiles[i]
-			comments.Trim(f)
-			destFilePath := filepath.Join(destPkgDir, filepath.Base(gofile))
+		syntax := pkg.Syntax
+		if verbatim.Contains(pkg) {
+			if err = writeVerbatim(pkg, destPkgDir, renamedExports); err != nil {
+				return
+			}
+			syntax = nil
+		}
+		// Renamed definitions and their uses are annotated with the indexes of their map entries.
+		annotations := make(map[*ast.Ident]int)
+		if cmdArgs.Annotate && !verbatim.Contains(pkg) {
+			for id := range pkg.TypesInfo.Defs {
+				if key, ok := defKeys[id]; ok {
+					annotations[id] = markers[key]
+				}
+			}
+			for id, obj := range pkg.TypesInfo.Uses {
+				if key, ok := objKeys[obj]; ok {
+					annotations[id] = markers[key]
+				}
+			}
+		}
+		tmpl := cmdArgs.FileNames.Template(pkg.PkgPath)
+		goFileNames := make(gg.Set[string])
+		// writeGoFile writes f, the i-th go file of pkg parsed from gofile.
+		writeGoFile := func(i int, f *ast.File, gofile string) (err error) {
+			name, err := goFileName(pkg, tmpl, i, gofile)
+			if err != nil {
+				return
+			}
+			if tmpl != nil {
+				if goFileNames.Contains(name) {
+					return fmt.Errorf("duplicated file name %v generated for package %v", name, pkg.PkgPath)
+				}
+				goFileNames.Add(name)
+			}
+			destFilePath := filepath.Join(destPkgDir, name)
+			if dryRun {
+				written = append(written, destFilePath)
+				return
+			}
 			if err = os.MkdirAll(filepath.Dir(destFilePath), 0777); err != nil {
 				return
 			}
+			end := rep.Begin(ctx, "format")
+			src, err := formatFile(pkg.Fset, f, destFilePath)
+			end()
+			if err != nil {
+				return
+			}
+			end = rep.Begin(ctx, "check")
+			err = snapshot.Check(pkg.Fset, f, src)
+			end()
+			if err != nil {
+				return
+			}
+			if cmdArgs.CheckFormat {
+				end = rep.Begin(ctx, "check-format")
+				err = checkFormat(destFilePath, src)
+				end()
+				if err != nil {
+					return
+				}
+			}
 			slog.Info("writing go file...\t", "path", destFilePath)
-			var w *os.File
-			w, err = os.OpenFile(destFilePath, os.O_CREATE|os.O_WRONLY|gg.If(cmdArgs.Force, os.O_TRUNC, os.O_EXCL), 0666)
+			w, err := createFile(destFilePath)
 			if err != nil {
 				return
 			}
-			if err = func() error {
-				defer gg.ChainError(w.Close, &err)
-				if err := doNotEdit(w); err != nil {
-					return err
+			_, err = w.Write(src)
+			return errors.Join(err, w.Close())
+		}
+		aliases := !verbatim.Contains(pkg) && writesAliases(pkg)
+		var prevAliases *ast.File // The aliases written by a previous run, which the new ones are appended to.
+		for i, f := range syntax {
+			gofile := pkg.CompiledGoFiles[i]
+			if !dryRun {
+				end = rep.Begin(ctx, "trim-comments")
+				if !rewriteTests || !isTestFile(gofile) {
+					comments.Trim(f)
 				}
-				if err := format.Node(w, pkg.Fset, f); err != nil {
-					return err
+				end()
+				if len(annotations) > 0 {
+					annotate.Annotate(f, annotations)
 				}
-				return nil
-			}(); err != nil {
+			}
+			if aliases && tmpl == nil && filepath.Base(gofile) == flags.CompatAliasesFile && generatedCode.Contains(f.Pos()) {
+				prevAliases = f
+				continue
+			}
+			if err = writeGoFile(i, f, gofile); err != nil {
+				return
+			}
+		}
+
+		// aliases of the original names of renamed exports
+		if aliases {
+			if goFileNames.Contains(flags.CompatAliasesFile) {
+				return fmt.Errorf("file name %v generated for package %v is used by -compat-aliases", flags.CompatAliasesFile, pkg.PkgPath)
+			}
+			if err = writeAliases(pkg, destPkgDir, newNames, secureObjects, prevAliases); err != nil {
 				return
 			}
 		}
 
+		// platform-specific files excluded from the build
+		end = rep.Begin(ctx, "siblings")
+		err = writeSiblings(pkg, siblingNames[pkg], exportedNames, !verbatim.Contains(pkg), len(syntax), snapshot, writeGoFile)
+		end()
+		if err != nil {
+			return
+		}
+
 		// other files
 		for _, f := range pkg.OtherFiles {
 			rel := gg.Must(filepath.Rel(pkg.Dir, f))
 			dest := filepath.Join(destPkgDir, rel)
 			slog.Info("copying other file...\t", "from", f, "to", dest)
-			if err = os2.CopyFile(f, dest, cmdArgs.Force); err != nil {
+			if err = copyFile(f, dest); err != nil {
 				return
 			}
 		}
 
+		// test files
+		if !cmdArgs.IncludeTests && cmdArgs.TestFiles == flags.CopyTests {
+			var files []string
+			if files, err = copiedTestFiles(pkg); err != nil {
+				return
+			}
+			for _, f := range files {
+				dest := filepath.Join(destPkgDir, filepath.Base(f))
+				slog.Info("copying test file...\t", "from", f, "to", dest)
+				if err = copyFile(f, dest); err != nil {
+					return
+				}
+			}
+		}
+
 		// embed files
 		for _, f := range pkg.EmbedFiles {
 			rel := gg.Must(filepath.Rel(pkg.Dir, f))
 			dest := filepath.Join(destPkgDir, rel)
 			slog.Info("copying embed file...\t", "from", f, "to", dest)
-			if err = os2.CopyFile(f, dest, cmdArgs.Force); err != nil {
+			if err = copyFile(f, dest); err != nil {
+				return
+			}
+		}
+
+		if dryRun {
+			return
+		}
+		if len(cmdArgs.PostFile) > 0 || len(cmdArgs.PostPackage) > 0 {
+			end = rep.Begin(ctx, "post-process")
+			err = postProcess(ctx, pkg, destPkgDir, written[firstWritten:])
+			end()
+			if err != nil {
 				return
 			}
 		}
+
+		if outputCache != nil {
+			var entry *cache.Entry
+			if entry, err = cacheEntry(pkg, cacheKeys[pkg], written[firstWritten:], pkgEntries[pkg], renamedExports); err != nil {
+				return
+			}
+			if entry != nil {
+				if err = outputCache.Put(entry); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}
+
+	// write
+	if !cmdArgs.InPlace {
+		if err = checkOutDir(loaded); err != nil {
+			return
+		}
+	}
+	mods, err := moduleFiles(loaded)
+	if err != nil {
+		return
+	}
+	// Packages not written and existing files kept, because the output files exist.
+	var skipped gg.Set[*packages.Package]
+	var kept gg.Set[string]
+	if !cmdArgs.Force {
+		end = rep.Begin(ctx, "conflicts")
+		c, err := findConflicts(loaded, writePackage, mods)
+		end()
+		if err != nil {
+			return err
+		}
+		if c.count() > 0 {
+			if skipped, kept, err = resolveConflicts(c); err != nil {
+				return err
+			}
+		}
+	}
+	// Messages are written after the conflicts are resolved, and before the packages
+	// are written, whose message literals are replaced.
+	if cmdArgs.MessagesFile != "" || cmdArgs.MessagePackage != "" {
+		end = rep.Begin(ctx, "messages")
+		err = extractMessages(slices.DeleteFunc(slices.Clone(loaded), verbatim.Contains), generatedCode)
+		end()
+		if err != nil {
+			return
+		}
+	}
+	end = rep.Begin(ctx, "module-files")
+	err = copyModuleFiles(mods, kept)
+	end()
+	if err != nil {
+		return
+	}
+	for _, pkg := range loaded {
+		destPkgDir := outDir(pkg.Dir)
+		if skipped.Contains(pkg) {
+			slog.Warn("package is not written, because its output files exist", "pkg", pkg.PkgPath, "dest", destPkgDir)
+			continue
+		}
+		slog.Info("writing package...\t", "pkg", pkg.PkgPath, "dest", destPkgDir)
+		if err = writePackage(pkg); err != nil {
+			return
+		}
+	}
+
+	if cmdArgs.MapFile != "" {
+		slog.Info("writing mapping file...\t", "path", cmdArgs.MapFile)
+		if mapKey != nil {
+			if err = renames.Sign(mapKey); err != nil {
+				return
+			}
+		}
+		if err = renames.Save(cmdArgs.MapFile); err != nil {
+			return
+		}
+	}
+	if cmdArgs.NameTable != "" {
+		if err = writeNameTable(nametable.NewTable(renames.Entries), tableKey); err != nil {
+			return
+		}
+	}
+	if cmdArgs.ManifestFile != "" || cmdArgs.SumsFile != "" {
+		slog.Info("writing manifest...\t", "path", cmdArgs.ManifestFile, "sha256sums", cmdArgs.SumsFile)
+		if err = writeManifest(cmdArgs.ManifestFile, cmdArgs.SumsFile, kept); err != nil {
+			return
+		}
 	}
 	return nil
 }
 
+// nopCloser is an io.WriteCloser whose Close does nothing.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// mkdirAll creates output directory dir, unless dryRun is set.
+func mkdirAll(dir string) error {
+	if dryRun {
+		return nil
+	}
+	return os.MkdirAll(dir, 0777)
+}
+
+// createFile creates output file path. Existing file is an error unless -overwrite is set.
+// The line endings of the content written are normalized as specified by -line-endings.
+// If dryRun is set, path is only recorded, and the content is discarded.
+func createFile(path string) (io.WriteCloser, error) {
+	if dryRun {
+		written = append(written, path)
+		return nopCloser{io.Discard}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|gg.If(cmdArgs.Force, os.O_TRUNC, os.O_EXCL), 0666)
+	if err == nil {
+		written = append(written, path)
+	}
+	if err != nil || cmdArgs.LineEndings == flags.PreserveLineEndings {
+		return f, err
+	}
+	return eol.NewWriter(f, cmdArgs.LineEndings == flags.CRLF), nil
+}
+
+// copyFile copies src to output file dest. Existing file is an error unless -overwrite is set.
+// The line endings of text files are normalized as specified by -line-endings.
+// If dryRun is set, dest is only recorded.
+func copyFile(src, dest string) (err error) {
+	if dryRun {
+		written = append(written, dest)
+		return nil
+	}
+	defer func() {
+		if err == nil {
+			written = append(written, dest)
+		}
+	}()
+	if cmdArgs.LineEndings == flags.PreserveLineEndings {
+		return os2.CopyFile(src, dest, cmdArgs.Force)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return
+	}
+	if !eol.IsText(data) {
+		return os2.CopyFile(src, dest, cmdArgs.Force)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return
+	}
+	w, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|gg.If(cmdArgs.Force, os.O_TRUNC, os.O_EXCL), info.Mode())
+	if err != nil {
+		return
+	}
+	defer gg.ChainError(w.Close, &err)
+	_, err = w.Write(eol.Convert(data, cmdArgs.LineEndings == flags.CRLF))
+	return
+}
+
+// goFileName returns the name of the output file of gofile, the i-th go file of pkg,
+// generated with tmpl, the template of -file-names for pkg, or the name of gofile if tmpl is nil.
+func goFileName(pkg *packages.Package, tmpl *template.Template, i int, gofile string) (string, error) {
+	name := filepath.Base(gofile)
+	if tmpl == nil {
+		return name, nil
+	}
+	return filename.Execute(tmpl, name, filename.NewData(i, name, pkg.Name, pkg.PkgPath))
+}
+
+// nameTableFile returns the path of the go file of -name-table.
+func nameTableFile() string {
+	dir := filepath.Join(cmdArgs.OutDir, cmdArgs.NameTable)
+	return filepath.Join(dir, filepath.Base(dir)+".go")
+}
+
+// writeNameTable writes the package of -name-table containing table, encrypted with key if not nil.
+func writeNameTable(table nametable.Table, key []byte) (err error) {
+	path := nameTableFile()
+	if err = os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return
+	}
+	name := filepath.Base(filepath.Dir(path))
+	slog.Info("writing name table...\t", "path", path)
+	w, err := createFile(path)
+	if err != nil {
+		return
+	}
+	defer gg.ChainError(w.Close, &err)
+	return nametable.Generate(w, name, table, key)
+}
+
 // filterPackages filter out the test binary package(pkg.test)
 // and the packages whose test package presents.
-func filterPackages(pkgs []*packages.Package) (result []*packages.Package) {
-	if !cmdArgs.IncludeTests {
-		result = pkgs
-		return
+func filterPackages(pkgs []*packages.Package) []*packages.Package {
+	if !loadTests() {
+		return pkgs
 	}
+	return filterTestPackages(pkgs)
+}
+
+// filterTestPackages filters out the test binary packages(pkg.test) and the packages
+// whose test packages present from pkgs, which are loaded with tests.
+func filterTestPackages(pkgs []*packages.Package) (result []*packages.Package) {
 	result = make([]*packages.Package, 0, len(pkgs))
 	var blackBoxTests []*packages.Package
 	for _, pkg := range pkgs {
@@ -279,8 +1076,99 @@ func filterPackages(pkgs []*packages.Package) (result []*packages.Package) {
 	return
 }
 
-func doNotEdit(f *os.File) (err error) {
+// loadTests returns whether test files are loaded, which is the case if they
+// are obfuscated or rewritten.
+func loadTests() bool {
+	return cmdArgs.IncludeTests || cmdArgs.TestFiles == flags.RewriteTests
+}
+
+// isTestFile returns whether file is a go test file.
+func isTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.go")
+}
+
+// copiedTestFiles returns the test files of pkg copied verbatim by -test-files copy.
+func copiedTestFiles(pkg *packages.Package) ([]string, error) {
+	return filepath.Glob(filepath.Join(pkg.Dir, "*_test.go"))
+}
+
+// pruneRoots parses the go files of pkg which are written but not type checked,
+// the platform-specific siblings and the test files copied verbatim,
+// whose references keep declarations of pkg from being pruned.
+func pruneRoots(pkg *packages.Package) (roots []*ast.File, err error) {
+	files := siblingFiles(pkg)
+	if !cmdArgs.IncludeTests && cmdArgs.TestFiles == flags.CopyTests {
+		var tests []string
+		if tests, err = copiedTestFiles(pkg); err != nil {
+			return
+		}
+		files = append(files, tests...)
+	}
+	fset := token.NewFileSet()
+	for _, gofile := range files {
+		var f *ast.File
+		if f, err = parser.ParseFile(fset, gofile, nil, parser.SkipObjectResolution); err != nil {
+			return
+		}
+		roots = append(roots, f)
+	}
+	return
+}
+
+// isExample returns whether pkg is in an example directory,
+// a directory named "examples" or "example" of its module.
+func isExample(pkg *packages.Package) bool {
+	rel := pkg.PkgPath
+	if pkg.Module != nil {
+		rel = strings.TrimPrefix(rel, pkg.Module.Path)
+	}
+	return slices.ContainsFunc(strings.Split(rel, "/"), func(elem string) bool {
+		return elem == "examples" || elem == "example"
+	})
+}
+
+// omitExamples filters out the example packages.
+func omitExamples(pkgs []*packages.Package) []*packages.Package {
+	return slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool {
+		if isExample(pkg) {
+			slog.Info("example package is omitted", "pkg", pkg.PkgPath)
+			return true
+		}
+		return false
+	})
+}
+
+// excludePackages filters out the packages matching any pattern of -exclude.
+func excludePackages(pkgs []*packages.Package) []*packages.Package {
+	return slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool {
+		for _, p := range cmdArgs.Exclude {
+			if pattern.Match(p, pkg.PkgPath, pkg.Dir) {
+				slog.Info("package is excluded", "pkg", pkg.PkgPath, "pattern", p)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// warnExcludedTests warns about the test files of pkgs, which are not written to the output
+// with -test-files=omit. Copying them as is would not compile, because they may reference
+// the original names of identifiers renamed in the package under test.
+func warnExcludedTests(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		if pkg.Dir == "" {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(pkg.Dir, "*_test.go"))
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		slog.Warn("test files are not written to output, use -include-test or -test-files to write them", "pkg", pkg.PkgPath, "files", len(files))
+	}
+}
+
+func doNotEdit(w io.Writer) (err error) {
 	// https://pkg.go.dev/cmd/go#hdr-Generate_Go_files_by_processing_source
-	_, err = io.WriteString(f, "// Code generated by goingbad. DO NOT EDIT.\n\n")
+	_, err = io.WriteString(w, "// Code generated by goingbad. DO NOT EDIT.\n\n")
 	return
 }
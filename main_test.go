@@ -1,7 +1,27 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/flags"
+	"github.com/mkch/iter2"
+	"golang.org/x/tools/go/packages"
 )
 
 func Test_internalPos(t *testing.T) {
@@ -52,3 +72,395 @@ func Test_canImport(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseVendoredModules(t *testing.T) {
+	const modules = `# github.com/a/b v1.0.0
+## explicit; go 1.21
+github.com/a/b
+github.com/a/b/c
+# golang.org/x/tools v0.32.0 => ../tools
+## explicit
+golang.org/x/tools/go/packages
+# example.com/replaced => ./local
+`
+	paths, err := parseVendoredModules(strings.NewReader(modules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"github.com/a/b", "golang.org/x/tools", "example.com/replaced"}; !slices.Equal(paths, want) {
+		t.Fatalf("want %v, got %v", want, paths)
+	}
+}
+
+func Test_promptConflicts(t *testing.T) {
+	tests := []struct {
+		input string
+		want  conflictAction
+	}{
+		{"o\n", overwriteConflicts},
+		{"Skip\n", skipConflicts},
+		{"\n", abortConflicts},
+		{"", abortConflicts},
+		{"yes\n a \n", abortConflicts},
+		{"x\ns\n", skipConflicts},
+	}
+	for _, tt := range tests {
+		var w strings.Builder
+		got, err := promptConflicts(strings.NewReader(tt.input), &w, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: want %v, got %v", tt.input, tt.want, got)
+		}
+		if !strings.HasPrefix(w.String(), "2 output files exist.") {
+			t.Errorf("%q: unexpected prompt %q", tt.input, w.String())
+		}
+	}
+}
+
+func Test_resolvePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0777); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(src, link); err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{src, src},
+		{link, src},
+		{filepath.Join(link, "a", "b"), filepath.Join(src, "a", "b")},
+		{filepath.Join(dir, "none", "a"), filepath.Join(dir, "none", "a")},
+	}
+	for _, tt := range tests {
+		got, err := resolvePath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("resolvePath(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_formatFile(t *testing.T) {
+	const src = `package a
+
+import (
+	"os"
+	"golang.org/x/tools/go/packages"
+	"fmt"
+)
+
+import "strings"
+
+var _ = fmt.Sprint(os.Args, packages.Package{}, strings.ToUpper)
+`
+	const want = `// Code generated by goingbad. DO NOT EDIT.
+
+package a
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+var _ = fmt.Sprint(os.Args, packages.Package{}, strings.ToUpper)
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := formatFile(fset, f, "a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, string(got))
+	}
+	if err := checkFormat("a.go", got); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFormat("a.go", []byte(src)); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func Test_isExample(t *testing.T) {
+	tests := []struct {
+		pkgPath string
+		module  string
+		want    bool
+	}{
+		{"example.com/m/examples/hello", "example.com/m", true},
+		{"example.com/m/example", "example.com/m", true},
+		{"example.com/m/examples", "example.com/m", true},
+		{"example.com/m/lib", "example.com/m", false},
+		{"example.com/m/examplesx", "example.com/m", false},
+		{"example/examples/a", "", true},
+		{"example/a", "example", false},
+	}
+	for _, tt := range tests {
+		pkg := &packages.Package{PkgPath: tt.pkgPath}
+		if tt.module != "" {
+			pkg.Module = &packages.Module{Path: tt.module}
+		}
+		if got := isExample(pkg); got != tt.want {
+			t.Errorf("isExample(%v) = %v, want %v", tt.pkgPath, got, tt.want)
+		}
+	}
+}
+
+func Test_verbatimFile(t *testing.T) {
+	const src = `package a
+
+// Helper is renamed in the other package.
+func Helper() int { return 1 }
+
+var x = Helper() + Helper()
+`
+	const want = `package a
+
+// Helper is renamed in the other package.
+func Helper() int { return 1 }
+
+var x = Renamed() + Renamed()
+`
+	gofile := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(gofile, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, gofile, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	if _, err = (&types.Config{}).Check("a", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+	def := f.Decls[0].(*ast.FuncDecl).Name
+	def.Name = "partiallyRenamed" // Not written.
+	pkg := &packages.Package{Fset: fset, TypesInfo: info, GoFiles: []string{gofile}}
+	got, err := verbatimFile(pkg, gofile, map[token.Pos]string{def.Pos(): "Renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("want\n%v\ngot\n%v", want, string(got))
+	}
+}
+
+func Test_dependencyOrder(t *testing.T) {
+	fmt := &packages.Package{PkgPath: "fmt"}
+	a := &packages.Package{PkgPath: "a", Imports: map[string]*packages.Package{"fmt": fmt}}
+	b := &packages.Package{PkgPath: "b", Imports: map[string]*packages.Package{"a": a, "fmt": fmt}}
+	c := &packages.Package{PkgPath: "c", Imports: map[string]*packages.Package{"b": b}}
+	got := slices.Collect(iter2.Map(slices.Values(dependencyOrder([]*packages.Package{c, b, a})),
+		func(pkg *packages.Package) string { return pkg.PkgPath }))
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func Test_unmatchedKeepNames(t *testing.T) {
+	check := func(path, src string) *packages.Package {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "a.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+		if _, err = (&types.Config{}).Check(path, fset, []*ast.File{f}, info); err != nil {
+			t.Fatal(err)
+		}
+		return &packages.Package{PkgPath: path, TypesInfo: info}
+	}
+	pkgs := []*packages.Package{
+		check("example.com/lib", "package lib\n\ntype Name struct{ Value int }\n"),
+		check("example.com/app", "package app\n\nfunc Run() {}\n"),
+	}
+	args, _, err := flags.Parse([]string{"-keep", "Name,lib.Value,Run,Naem,lib.Naem,lbi.Name,app.Unknown", "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := unmatchedKeepNames(pkgs, args.KeepNames.All())
+	want := []unmatchedKeep{
+		{"Naem", []string{"Name"}},
+		{"app.Unknown", nil},
+		{"lbi.Name", []string{"lib.Name"}},
+		{"lib.Naem", []string{"lib.Name"}},
+	}
+	if !slices.EqualFunc(got, want, func(a, b unmatchedKeep) bool {
+		return a.Name == b.Name && slices.Equal(a.Suggestions, b.Suggestions)
+	}) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+var digestFile = flag.String("digest", "", "File to write the digest of the output of Test_determinism to, to compare runs on different systems.")
+
+// Test_determinism runs the whole pipeline on testdata/src twice,
+// and checks that the output trees and the mapping files are byte-identical.
+func Test_determinism(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loads packages with the go command")
+	}
+	out := t.TempDir()
+	t.Chdir("testdata/src")
+	var trees [2]map[string]string
+	for i := range trees {
+		dir := filepath.Join(out, strconv.Itoa(i))
+		args, patterns, err := flags.Parse([]string{
+			"-o", filepath.Join(dir, "out"), "-map", filepath.Join(dir, "map.json"),
+			"-oie", "-json-tags", "-test-files", "rewrite", "./...",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmdArgs = args
+		if err = obfuscate(patterns); err != nil {
+			t.Fatal(err)
+		}
+		if trees[i], err = readTree(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range trees[0] {
+		if other, ok := trees[1][name]; !ok {
+			t.Errorf("%v is missing in the second run", name)
+		} else if other != content {
+			t.Errorf("%v differs:\n%v\n%v", name, content, other)
+		}
+	}
+	for name := range trees[1] {
+		if _, ok := trees[0][name]; !ok {
+			t.Errorf("%v is missing in the first run", name)
+		}
+	}
+	if *digestFile != "" {
+		if err := os.WriteFile(*digestFile, []byte(treeDigest(trees[0])+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Test_lineEndings runs the whole pipeline on testdata/src with each of -line-endings,
+// and checks the line endings of all the text files written.
+func Test_lineEndings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loads packages with the go command")
+	}
+	out := t.TempDir()
+	t.Chdir("testdata/src")
+	for _, endings := range []flags.LineEndings{flags.LF, flags.CRLF} {
+		dir := filepath.Join(out, string(endings))
+		args, patterns, err := flags.Parse([]string{"-o", dir, "-test-files", "copy", "-line-endings", string(endings), "./..."})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmdArgs = args
+		if err = obfuscate(patterns); err != nil {
+			t.Fatal(err)
+		}
+		tree, err := readTree(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range tree {
+			if strings.HasPrefix(content, "\xEF\xBB\xBF") {
+				t.Errorf("%v: %v has byte order mark", endings, name)
+			}
+			lines := strings.Count(content, "\n")
+			if crlfs := strings.Count(content, "\r\n"); endings == flags.LF && crlfs != 0 || endings == flags.CRLF && crlfs != lines {
+				t.Errorf("%v: %v has %v CRLF in %v lines", endings, name, crlfs, lines)
+			}
+		}
+	}
+}
+
+// Test_cache runs the whole pipeline with -cache on a copy of testdata/src, changes a package,
+// and checks that the output of the incremental run is the same as the output without cache.
+func Test_cache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loads packages with the go command")
+	}
+	out := t.TempDir()
+	src := filepath.Join(out, "src")
+	if err := os.CopyFS(src, os.DirFS("testdata/src")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(src)
+	run := func(name string, cache bool) map[string]string {
+		dir := filepath.Join(out, name)
+		argv := []string{"-o", filepath.Join(dir, "out"), "-map", filepath.Join(dir, "map.json"), "-oie", "-test-files", "rewrite"}
+		if cache {
+			argv = append(argv, "-cache", filepath.Join(out, "cache"))
+		}
+		args, patterns, err := flags.Parse(append(argv, "./..."))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmdArgs = args
+		if err = obfuscate(patterns); err != nil {
+			t.Fatal(err)
+		}
+		tree, err := readTree(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+	run("first", true)
+	f, err := os.OpenFile("b.go", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString("\nfunc added() int { return 1 }\n")
+	if err = errors.Join(err, f.Close()); err != nil {
+		t.Fatal(err)
+	}
+	got, want := run("incremental", true), run("fresh", false)
+	if !maps.Equal(got, want) {
+		t.Fatalf("incremental output differs:\n%v\n%v", got, want)
+	}
+}
+
+// readTree returns the contents of the regular files in dir, keyed by slash-separated relative paths.
+func readTree(dir string) (tree map[string]string, err error) {
+	tree = make(map[string]string)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tree[filepath.ToSlash(gg.Must(filepath.Rel(dir, path)))] = string(content)
+		return nil
+	})
+	return
+}
+
+// treeDigest returns the hex encoded SHA-256 of the names and contents of tree.
+func treeDigest(tree map[string]string) string {
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(tree)) {
+		h.Write([]byte(name + "\x00" + tree[name] + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/manifest"
+)
+
+// writeManifest writes the manifest of the written files in the output directory to file,
+// and the same hashes in the format of sha256sum to sumsFile. Either file can be empty.
+// The existing files kept by -i are included, which are part of the output too.
+// So is sumsFile if it is in the output directory.
+func writeManifest(file, sumsFile string, kept gg.Set[string]) (err error) {
+	root := gg.Must(filepath.Abs(cmdArgs.OutDir))
+	inRoot := func(abs string) bool {
+		rel, err := filepath.Rel(root, abs)
+		return err == nil && filepath.IsLocal(rel)
+	}
+	var files []string
+	for _, f := range slices.Concat(written, slices.Sorted(maps.Keys(kept))) {
+		if abs := gg.Must(filepath.Abs(f)); inRoot(abs) {
+			files = append(files, abs)
+		}
+	}
+	m, err := manifest.New(root, files)
+	if err != nil {
+		return
+	}
+	if sumsFile != "" {
+		if err = writeFile(sumsFile, func(w io.Writer) error { return manifest.WriteSums(w, m) }); err != nil {
+			return
+		}
+		if abs := gg.Must(filepath.Abs(sumsFile)); file != "" && inRoot(abs) {
+			if err = manifest.Add(m, root, abs); err != nil {
+				return
+			}
+		}
+	}
+	if file == "" {
+		return
+	}
+	return writeFile(file, func(w io.Writer) error { return manifest.Write(w, m) })
+}
+
+// writeFile writes file with write. An existing file is an error unless -overwrite is set.
+func writeFile(file string, write func(w io.Writer) error) (err error) {
+	w, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|gg.If(cmdArgs.Force, os.O_TRUNC, os.O_EXCL), 0666)
+	if err != nil {
+		return
+	}
+	defer gg.ChainError(w.Close, &err)
+	return write(w)
+}
+
+// verify implements the verify subcommand, which reports the files of an output
+// directory modified, missing or added since the manifest was written with -manifest:
+//
+//	goingbad verify [-dir output_dir] manifest
+//
+// The directory defaults to the one of the manifest. The exit code is exitMismatch
+// if any file is reported.
+func verify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	dir := flags.String("dir", "", "Path to the output directory. Defaults to the directory of the manifest.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if args = flags.Args(); len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: goingbad verify [-dir output_dir] manifest")
+		return exitUsage
+	}
+	file := args[0]
+	if *dir == "" {
+		*dir = filepath.Dir(file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	hashes, err := manifest.Read(f)
+	f.Close()
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	// The manifest itself is not in the manifest.
+	self, _ := filepath.Rel(gg.Must(filepath.Abs(*dir)), gg.Must(filepath.Abs(file)))
+	problems, err := manifest.Verify(*dir, hashes, func(path string) bool { return path == filepath.ToSlash(self) })
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	for _, p := range problems {
+		fmt.Printf("%v\t%v\n", p.Kind, p.Path)
+	}
+	if len(problems) > 0 {
+		return exitMismatch
+	}
+	return exitOK
+}
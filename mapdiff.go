@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/mkch/goingbad/internal/mapping"
+)
+
+// mapDiff implements the mapdiff subcommand, which reports the identifiers whose
+// obfuscated names changed, were added or removed between two mapping files:
+//
+//	goingbad mapdiff [-map-key file] old.json new.json
+//
+// With -map-key, both files must be signed with the key.
+func mapDiff(args []string) int {
+	flags := flag.NewFlagSet("mapdiff", flag.ContinueOnError)
+	keyFile := flags.String("map-key", "", "Path to the file of the key the mapping files are signed with.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if args = flags.Args(); len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: goingbad mapdiff [-map-key file] old.json new.json")
+		return exitUsage
+	}
+	var key []byte
+	if *keyFile != "" {
+		var err error
+		if key, err = mapping.ReadKey(*keyFile); err != nil {
+			slog.Error(err.Error())
+			return exitFailure
+		}
+	}
+	old, err := mapping.Load(args[0], key)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	new, err := mapping.Load(args[1], key)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	printMapDiff(os.Stdout, old, new)
+	return exitOK
+}
+
+func printMapDiff(w io.Writer, old, new *mapping.Map) {
+	counts := make(map[mapping.ChangeKind]int)
+	for _, change := range mapping.Diff(old, new) {
+		counts[change.Kind]++
+		switch change.Kind {
+		case mapping.Changed:
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v -> %v\n", change.Kind, change.New.Package, change.New.Key, change.Old.New, change.New.New)
+		case mapping.Added:
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", change.Kind, change.New.Package, change.New.Key, change.New.New)
+		case mapping.Removed:
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", change.Kind, change.Old.Package, change.Old.Key, change.Old.New)
+		}
+	}
+	unchanged := len(new.Entries) - counts[mapping.Changed] - counts[mapping.Added]
+	fmt.Fprintf(w, "%d changed, %d added, %d removed, %d unchanged\n",
+		counts[mapping.Changed], counts[mapping.Added], counts[mapping.Removed], unchanged)
+}
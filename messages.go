@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/generated"
+	"github.com/mkch/goingbad/internal/messages"
+	"golang.org/x/tools/go/packages"
+)
+
+// isGeneratedMessage returns whether lit of pkg is in generatedCode, or passed along with the lookup
+// of a generated message package, as the format "%s" replacing a format literal.
+func isGeneratedMessage(pkg *packages.Package, lit messages.Literal, generatedCode *generated.Registry) bool {
+	if generatedCode.Contains(lit.Call.Pos()) {
+		return true
+	}
+	return slices.ContainsFunc(lit.Call.Args, func(arg ast.Expr) bool {
+		call, ok := ast.Unparen(arg).(*ast.CallExpr)
+		return ok && generatedCode.Calls(pkg.TypesInfo, call)
+	})
+}
+
+// extractMessages writes the catalog of the message literals of pkgs to the file of -messages.
+// With -message-package, the lookup package is generated and the literals are replaced with lookups.
+// The literals in generatedCode, and the ones already replaced by a previous run, are skipped.
+func extractMessages(pkgs []*packages.Package, generatedCode *generated.Registry) (err error) {
+	var catalog messages.Catalog
+	found := make(map[*packages.Package][]messages.Literal)
+	for _, pkg := range pkgs {
+		lits := slices.DeleteFunc(messages.Find(pkg), func(lit messages.Literal) bool {
+			return isGeneratedMessage(pkg, lit, generatedCode)
+		})
+		catalog.Add(pkg, lits)
+		found[pkg] = lits
+	}
+	if cmdArgs.MessagesFile != "" {
+		slog.Info("writing message catalog...\t", "path", cmdArgs.MessagesFile)
+		if err = catalog.Save(cmdArgs.MessagesFile); err != nil {
+			return
+		}
+	}
+	if cmdArgs.MessagePackage == "" {
+		return nil
+	}
+
+	name := filepath.Base(cmdArgs.MessagePackage)
+	mod, importPath := messagePackagePath(pkgs)
+	if mod == nil {
+		return fmt.Errorf("-message-package %v is not in the module of any package", cmdArgs.MessagePackage)
+	}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == importPath {
+			return fmt.Errorf("-message-package %v is the directory of package %v", cmdArgs.MessagePackage, pkg.PkgPath)
+		}
+	}
+	for _, pkg := range pkgs {
+		if len(found[pkg]) == 0 {
+			continue
+		}
+		if pkg.Module == nil || pkg.Module.Path != mod.Path {
+			slog.Warn("messages are not replaced, the package can not import "+importPath, "pkg", pkg.PkgPath)
+			continue
+		}
+		if !messages.Replace(pkg, found[pkg], name, importPath) {
+			slog.Warn("messages are not replaced, "+name+" is an identifier of the package", "pkg", pkg.PkgPath)
+		}
+	}
+
+	file := messagePackageFile()
+	if err = os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return
+	}
+	slog.Info("writing message package...\t", "path", file)
+	w, err := createFile(file)
+	if err != nil {
+		return
+	}
+	defer gg.ChainError(w.Close, &err)
+	return messages.Generate(w, name, &catalog)
+}
+
+// messagePackageFile returns the path of the go file of -message-package.
+func messagePackageFile() string {
+	dir := filepath.Join(cmdArgs.OutDir, cmdArgs.MessagePackage)
+	return filepath.Join(dir, filepath.Base(dir)+".go")
+}
+
+// messagePackagePath returns the module containing the package of -message-package,
+// which is relative to the output directory as the packages are to current directory,
+// and the import path of the package. Mod is nil if there is no such module in pkgs.
+func messagePackagePath(pkgs []*packages.Package) (mod *packages.Module, importPath string) {
+	dir := gg.Must(filepath.Abs(cmdArgs.MessagePackage))
+	for _, pkg := range pkgs {
+		if pkg.Module == nil || pkg.Module.Dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(pkg.Module.Dir, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return pkg.Module, path.Join(pkg.Module.Path, filepath.ToSlash(rel))
+	}
+	return nil, ""
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkch/gg"
+	filepath2 "github.com/mkch/gg/filepath"
+	"golang.org/x/tools/go/packages"
+)
+
+// thirdPartyDir is the directory in output module where the
+// license files of vendored modules are copied to.
+// Vendored modules are not copied to "vendor", because the go command
+// would complain about inconsistent vendoring without the vendored code.
+const thirdPartyDir = "third_party"
+
+// moduleFile is a file copied to the output directory by copyModuleFiles.
+type moduleFile struct {
+	from, to string
+}
+
+// moduleFiles returns go.mod, go.sum and the files matching -module-files in the root
+// directory of the modules of pkgs, and the paths in the output directory they are copied to.
+// Files matching -module-files in the root directories of vendored modules are copied to
+// thirdPartyDir.
+func moduleFiles(pkgs []*packages.Package) (files []moduleFile, err error) {
+	copied := make(gg.Set[string])
+	for _, pkg := range pkgs {
+		mod := pkg.Module
+		if mod == nil || mod.Dir == "" || copied.Contains(mod.Dir) {
+			continue
+		}
+		copied.Add(mod.Dir)
+		if isOutside(mod.Dir) {
+			slog.Info("module root is outside of current directory, not copied", "module", mod.Path)
+			continue
+		}
+		dest := outDir(mod.Dir)
+		if mod.GoMod != "" {
+			for _, file := range []string{mod.GoMod, filepath2.ChangeExt(mod.GoMod, ".sum")} {
+				if _, statErr := os.Stat(file); statErr != nil {
+					continue
+				}
+				files = append(files, moduleFile{file, filepath.Join(dest, filepath.Base(file))})
+			}
+		}
+		if files, err = appendMatchingFiles(files, mod.Dir, dest); err != nil {
+			return
+		}
+
+		vendored, err := readVendoredModules(filepath.Join(mod.Dir, "vendor", "modules.txt"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range vendored {
+			files, err = appendMatchingFiles(files,
+				filepath.Join(mod.Dir, "vendor", filepath.FromSlash(path)),
+				filepath.Join(dest, thirdPartyDir, filepath.FromSlash(path)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// copyModuleFiles copies files, returned by moduleFiles, to the output directory.
+// The files whose destinations are in kept are not copied, and the existing ones are kept.
+func copyModuleFiles(files []moduleFile, kept gg.Set[string]) error {
+	for _, file := range files {
+		if kept.Contains(file.to) {
+			slog.Info("keeping existing module file...\t", "path", file.to)
+			continue
+		}
+		slog.Info("copying module file...\t", "from", file.from, "to", file.to)
+		if err := os.MkdirAll(filepath.Dir(file.to), 0777); err != nil {
+			return err
+		}
+		if err := copyFile(file.from, file.to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outDir returns the output directory of src directory.
+func outDir(src string) string {
+	return filepath.Join(cmdArgs.OutDir, gg.Must(filepath.Rel(gg.Must(filepath.Abs("")), src)))
+}
+
+// isOutside returns whether dir is outside of current directory.
+func isOutside(dir string) bool {
+	rel := gg.Must(filepath.Rel(gg.Must(filepath.Abs("")), dir))
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkOutDir returns an error if the output directory of any package or module of pkgs
+// is the directory of the source files of pkgs, so the sources would be overwritten.
+// Symbolic links are resolved before comparing.
+func checkOutDir(pkgs []*packages.Package) error {
+	srcDirs := make(map[string]string) // Resolved to original.
+	var dirs []string
+	for _, pkg := range pkgs {
+		dirs = append(dirs, pkg.Dir)
+		// Module roots outside of current directory are not copied.
+		if mod := pkg.Module; mod != nil && mod.Dir != "" && !isOutside(mod.Dir) {
+			dirs = append(dirs, mod.Dir)
+		}
+	}
+	for _, dir := range dirs {
+		resolved, err := resolvePath(dir)
+		if err != nil {
+			return err
+		}
+		srcDirs[resolved] = dir
+	}
+	for _, dir := range dirs {
+		resolved, err := resolvePath(outDir(dir))
+		if err != nil {
+			return err
+		}
+		if src, ok := srcDirs[resolved]; ok {
+			return fmt.Errorf("output directory %v is the source directory %v, use -in-place to write into it", outDir(dir), src)
+		}
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path of path with symbolic links resolved.
+// The nonexistent trailing elements of path are kept as is.
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	if parent, err = resolvePath(parent); err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
+
+// appendMatchingFiles appends the regular files matching -module-files in src directory to files,
+// to be copied to dest directory.
+func appendMatchingFiles(files []moduleFile, src, dest string) ([]moduleFile, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && matchModuleFile(entry.Name()) {
+			files = append(files, moduleFile{filepath.Join(src, entry.Name()), filepath.Join(dest, entry.Name())})
+		}
+	}
+	return files, nil
+}
+
+// matchModuleFile returns whether name matches any of the patterns of -module-files.
+func matchModuleFile(name string) bool {
+	for pattern := range strings.SplitSeq(cmdArgs.ModuleFiles, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readVendoredModules returns the paths of modules listed in vendor/modules.txt.
+// The result is nil if file does not exist.
+func readVendoredModules(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parseVendoredModules(f)
+}
+
+// parseVendoredModules parses the content of vendor/modules.txt.
+// Module lines are in the form of
+//
+//	# module/path version
+func parseVendoredModules(r io.Reader) (paths []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		fields := strings.Fields(line[2:])
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		paths = append(paths, fields[0])
+	}
+	return paths, scanner.Err()
+}
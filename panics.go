@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mkch/goingbad/internal/renamer"
+	"github.com/mkch/goingbad/internal/report"
+	"golang.org/x/tools/go/packages"
+)
+
+// panickedPackage logs the panics recovered while renaming pkg, and returns them for the report.
+// Failed is whether pkg is given up because of too many panics.
+func panickedPackage(pkg *packages.Package, panics []renamer.Panic, failed bool) report.PanickedPackage {
+	result := report.PanickedPackage{Package: pkg.PkgPath, Failed: failed}
+	for _, p := range panics {
+		position := pkg.Fset.Position(p.Pos)
+		slog.Error("internal error, declaration is not obfuscated", "pos", position, "name", p.Name, "panic", p.Value)
+		slog.Debug("stack of internal error", "pos", position, "stack", string(p.Stack))
+		result.Panics = append(result.Panics, report.RecoveredPanic{
+			Position: position.String(),
+			Name:     p.Name,
+			Panic:    fmt.Sprint(p.Value),
+		})
+	}
+	return result
+}
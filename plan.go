@@ -0,0 +1,46 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/flags"
+	"github.com/mkch/goingbad/internal/plan"
+	"golang.org/x/tools/go/packages"
+)
+
+// printPlan prints the estimated work of the packages matching patterns, for -plan.
+// Packages are parsed but not type checked, and filtered as they are by obfuscation.
+func printPlan(patterns []string) error {
+	const mode = packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedSyntax |
+		packages.NeedModule
+	loaded, err := packages.Load(&packages.Config{
+		Mode:  mode | gg.If(loadTests(), packages.NeedForTest, 0),
+		Tests: loadTests()}, patterns...)
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		return errors.New("no package loaded")
+	}
+	// Type errors are not found without type checking.
+	logPackageErrors(loaded)
+	loaded = filterPackages(loaded)
+	if cmdArgs.Examples == flags.OmitExamples {
+		loaded = omitExamples(loaded)
+	}
+	if len(cmdArgs.Exclude) > 0 {
+		loaded = excludePackages(loaded)
+	}
+	var estimates []*plan.Package
+	for _, pkg := range loaded {
+		estimates = append(estimates, plan.Estimate(pkg))
+	}
+	slices.SortFunc(estimates, func(a, b *plan.Package) int { return cmp.Compare(a.Path, b.Path) })
+	return plan.Write(os.Stdout, estimates)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/postproc"
+	"golang.org/x/tools/go/packages"
+)
+
+// postProcess runs the -post-file commands on files written for pkg to destPkgDir,
+// and then the -post-package commands on destPkgDir.
+//
+// The files the commands create in destPkgDir are added to written. Changing the other
+// existing files in destPkgDir, which are not written for pkg, is an error unless
+// -overwrite is set, as writing them would be.
+func postProcess(ctx context.Context, pkg *packages.Package, destPkgDir string, files []string) error {
+	before, err := modTimes(destPkgDir)
+	if err != nil {
+		return err
+	}
+	dir := gg.Must(filepath.Abs(destPkgDir))
+	for _, file := range files {
+		data := &postproc.Data{File: gg.Must(filepath.Abs(file)), Dir: dir, Package: pkg.PkgPath, Name: pkg.Name}
+		for _, cmd := range cmdArgs.PostFile {
+			slog.Info("post-processing file...\t", "path", file, "command", cmd)
+			if err := cmd.Run(ctx, data); err != nil {
+				return err
+			}
+		}
+	}
+	data := &postproc.Data{Dir: dir, Package: pkg.PkgPath, Name: pkg.Name}
+	for _, cmd := range cmdArgs.PostPackage {
+		slog.Info("post-processing package...\t", "dir", destPkgDir, "command", cmd)
+		if err := cmd.Run(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	after, err := modTimes(destPkgDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range slices.Sorted(maps.Keys(after)) {
+		if slices.Contains(files, file) {
+			continue
+		}
+		modTime, existed := before[file]
+		if !existed {
+			slog.Info("file created by post-processing", "pkg", pkg.PkgPath, "path", file)
+			written = append(written, file)
+		} else if !cmdArgs.Force && !modTime.Equal(after[file]) {
+			return conflictError{fmt.Errorf("existing output file %v is changed by post-processing, use -overwrite to allow it", file)}
+		}
+	}
+	return nil
+}
+
+// modTimes returns the modification times of the regular files in dir, by their paths.
+func modTimes(dir string) (map[string]time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]time.Time)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		result[filepath.Join(dir, entry.Name())] = info.ModTime()
+	}
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/mkch/goingbad/internal/flags"
+	"github.com/mkch/goingbad/internal/quarantine"
+	"github.com/mkch/goingbad/internal/report"
+	"golang.org/x/tools/go/packages"
+)
+
+// quarantineReasons returns the detectors of -quarantine which quarantine pkg.
+// Pkg is not quarantined if the result is empty.
+func quarantineReasons(pkg *packages.Package) (reasons []report.QuarantineReason) {
+	detectors := []struct {
+		name  string
+		count func() int
+	}{
+		{flags.CgoQuarantine, func() int { return quarantine.CgoFiles(pkg) }},
+		{flags.UnsafeQuarantine, func() int { return quarantine.Uses(pkg, "unsafe") }},
+		{flags.ReflectQuarantine, func() int { return quarantine.Uses(pkg, "reflect") }},
+	}
+	for _, d := range detectors {
+		threshold, ok := cmdArgs.Quarantine.Threshold(d.name)
+		if !ok {
+			continue
+		}
+		if n := d.count(); n >= threshold {
+			reasons = append(reasons, report.QuarantineReason{Detector: d.name, Count: n, Threshold: threshold})
+		}
+	}
+	return
+}
@@ -0,0 +1,186 @@
+// Package schema defines the JSON formats of the files written by goingbad,
+// so they can be read by other tools:
+//
+//   - [Mapping], the original and obfuscated names written with -map.
+//   - [Report], the findings and timings of a run written with -report.
+//   - [Messages], the catalog of user-facing messages written with -messages.
+//   - [Manifest], the hashes of the output files written with -manifest.
+//
+// Every file starts with a [Header], which identifies its kind and the version
+// of the schema. Fields may be added to a kind without changing the version,
+// and readers must ignore the fields they do not know, which encoding/json does
+// by default. The version is increased only for incompatible changes, which
+// readers of older versions reject with [Header.Check].
+package schema
+
+import (
+	"fmt"
+	"time"
+)
+
+// Version is the current version of the schema.
+const Version = 1
+
+// Kinds of files.
+const (
+	MappingKind  = "mapping"
+	ReportKind   = "report"
+	MessagesKind = "messages"
+	ManifestKind = "manifest"
+)
+
+// Header is the common fields of all files.
+type Header struct {
+	Kind    string `json:"kind"`
+	Version int    `json:"version"`
+}
+
+// NewHeader returns the header of kind in the current version.
+func NewHeader(kind string) Header {
+	return Header{Kind: kind, Version: Version}
+}
+
+// Check returns an error if h is not of kind, or its version is newer than [Version].
+func (h Header) Check(kind string) error {
+	if h.Kind != kind {
+		return fmt.Errorf("not a %v file: kind %q", kind, h.Kind)
+	}
+	if h.Version > Version {
+		return fmt.Errorf("unsupported %v version %v, the latest supported version is %v", kind, h.Version, Version)
+	}
+	return nil
+}
+
+// MappingEntry is a renamed identifier.
+type MappingEntry struct {
+	Package string `json:"package"` // Import path of the package where the identifier is defined.
+	Key     string `json:"key"`     // Original qualified name of the identifier in package.
+	Old     string `json:"old"`     // Original name.
+	New     string `json:"new"`     // Obfuscated name.
+}
+
+// Mapping is the mapping of all renamed identifiers of a run.
+//
+// The key of an identifier is its qualified name in the package, which stays the
+// same as long as the declaration is not renamed or moved:
+//
+//	Name                 package level identifier
+//	Type.Name            method, field or interface method
+//	Type.Field.Name      field of anonymous struct type of a field
+//	Func.Name            local identifier, where Func can also be Type.Method
+//	Func.Name#2          second local identifier with the same name in Func
+//
+// Signature is present if the file is signed with a key. It is the hex encoded
+// HMAC-SHA256 of the compact JSON encoding of an object with the fields
+// "kind", "version", "salt" and "lineage" if present, and "entries" of the file,
+// in this order.
+type Mapping struct {
+	Header
+	Salt      string           `json:"salt,omitempty"`    // Salt of the new names of exported identifiers, see -salt.
+	Lineage   []MappingRelease `json:"lineage,omitempty"` // Mapping files of the previous runs, the latest last.
+	Entries   []MappingEntry   `json:"entries"`
+	Signature string           `json:"signature,omitempty"`
+}
+
+// MappingRelease identifies the mapping file of a previous run, see -previous-map.
+type MappingRelease struct {
+	Salt   string `json:"salt,omitempty"` // Salt of the run.
+	Digest string `json:"digest"`         // Hex encoded SHA-256 of the mapping file.
+}
+
+// Kinds of hotspots.
+const (
+	UnsafePointerHotspot = "unsafe-pointer" // Conversion through unsafe.Pointer.
+	TypeSetHotspot       = "type-set"       // Struct type in the type set of a constraint, whose fields keep their names.
+	AssemblyHotspot      = "assembly"       // Function implemented in assembly, which keeps its name.
+)
+
+// Hotspot is a piece of code which resists obfuscation.
+type Hotspot struct {
+	Package  string   `json:"package"`
+	Position string   `json:"position"`
+	Kind     string   `json:"kind"`
+	Types    []string `json:"types,omitempty"` // Types involved.
+	Message  string   `json:"message"`
+}
+
+// Pass is the time spent in a pass of a run.
+type Pass struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"` // In nanoseconds.
+}
+
+// PartialPackage is a package with errors, which is partially obfuscated with -best-effort.
+type PartialPackage struct {
+	Package string   `json:"package"`
+	Errors  []string `json:"errors"`
+	Kept    []string `json:"kept"` // Names not obfuscated because they may be referenced by unresolved identifiers.
+}
+
+// RecoveredPanic is an internal error recovered while renaming a declaration,
+// which keeps its name.
+type RecoveredPanic struct {
+	Position string `json:"position"`
+	Name     string `json:"name"`
+	Panic    string `json:"panic"`
+}
+
+// PanickedPackage is a package in which internal errors are recovered.
+type PanickedPackage struct {
+	Package string           `json:"package"`
+	Failed  bool             `json:"failed"` // Copied verbatim because of more panics than -max-panics.
+	Panics  []RecoveredPanic `json:"panics"`
+}
+
+// QuarantineReason is a detector which quarantined a package.
+type QuarantineReason struct {
+	Detector  string `json:"detector"`
+	Count     int    `json:"count"` // Occurrences found.
+	Threshold int    `json:"threshold"`
+}
+
+// QuarantinedPackage is a package copied verbatim instead of obfuscated with -quarantine.
+type QuarantinedPackage struct {
+	Package string             `json:"package"`
+	Reasons []QuarantineReason `json:"reasons"`
+}
+
+// Report is the findings of a run.
+type Report struct {
+	Header
+	Hotspots    []Hotspot            `json:"hotspots"`
+	Passes      []Pass               `json:"passes"` // In the order of first run.
+	Partial     []PartialPackage     `json:"partial,omitempty"`
+	Panicked    []PanickedPackage    `json:"panicked,omitempty"`
+	Quarantined []QuarantinedPackage `json:"quarantined,omitempty"`
+}
+
+// Message is a user-facing message literal, such as the text of errors.New and
+// the format of fmt.Errorf.
+type Message struct {
+	// ID is stable as long as the package and the text do not change.
+	// It is the first 8 bytes of the SHA-256 of the import path, a zero byte
+	// and the text, hex encoded.
+	ID        string   `json:"id"`
+	Package   string   `json:"package"`
+	Text      string   `json:"text"`
+	Positions []string `json:"positions"` // file:line:column, where file is the base name.
+}
+
+// Messages is the catalog of the user-facing messages of a run.
+type Messages struct {
+	Header
+	Messages []Message `json:"messages"` // Ordered by package and ID.
+}
+
+// ManifestFile is a file written to the output directory.
+type ManifestFile struct {
+	Path   string `json:"path"`   // Slash separated path relative to the output directory.
+	SHA256 string `json:"sha256"` // Hex encoded SHA-256 of the content.
+}
+
+// Manifest is the hashes of the files written to the output directory by a run.
+type Manifest struct {
+	Header
+	Files []ManifestFile `json:"files"` // Ordered by paths.
+}
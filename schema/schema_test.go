@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_Header_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  Header
+		kind    string
+		wantErr bool
+	}{
+		{"current", NewHeader(MappingKind), MappingKind, false},
+		{"older", Header{MappingKind, Version - 1}, MappingKind, false},
+		{"newer", Header{MappingKind, Version + 1}, MappingKind, true},
+		{"kind", NewHeader(ReportKind), MappingKind, true},
+		{"no header", Header{}, MappingKind, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.header.Check(tt.kind); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test_forwardCompatible checks that files with fields added in later revisions
+// of the same version can be read.
+func Test_forwardCompatible(t *testing.T) {
+	const mapping = `{
+	"kind": "mapping",
+	"version": 1,
+	"tool": "goingbad vNext",
+	"entries": [
+		{"package": "a", "key": "T.f", "old": "f", "new": "b", "scope": "field"}
+	]
+}`
+	var m Mapping
+	if err := json.Unmarshal([]byte(mapping), &m); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(MappingKind); err != nil {
+		t.Fatal(err)
+	}
+	if want := (MappingEntry{Package: "a", Key: "T.f", Old: "f", New: "b"}); len(m.Entries) != 1 || m.Entries[0] != want {
+		t.Fatalf("want %v, got %v", want, m.Entries)
+	}
+
+	const report = `{
+	"kind": "report",
+	"version": 1,
+	"hotspots": [{"package": "a", "position": "a.go:1:1", "kind": "reflection", "message": "m", "severity": 2}],
+	"passes": [{"name": "rename", "duration": 10, "allocs": 3}],
+	"packages": []
+}`
+	var r Report
+	if err := json.Unmarshal([]byte(report), &r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Check(ReportKind); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Hotspots) != 1 || r.Hotspots[0].Kind != "reflection" || len(r.Passes) != 1 || r.Passes[0].Duration != 10 {
+		t.Fatalf("got %+v", r)
+	}
+}
+
+func Test_marshal(t *testing.T) {
+	data, err := json.Marshal(Mapping{Header: NewHeader(MappingKind)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"kind":"mapping","version":1,"entries":null}`; string(data) != want {
+		t.Fatalf("want %v, got %v", want, string(data))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"log/slog"
+	"path"
+	"path/filepath"
+
+	"github.com/mkch/goingbad/internal/comments"
+	"github.com/mkch/goingbad/internal/filename"
+	"github.com/mkch/goingbad/internal/invariant"
+	"github.com/mkch/goingbad/internal/sibling"
+	"golang.org/x/tools/go/packages"
+)
+
+// addSiblingName adds obj of pkg renamed to newName to names, and to exported
+// if obj is an exported package level declaration.
+func addSiblingName(pkg *packages.Package, names *sibling.Names, exported map[string]map[string]string, obj types.Object, newName string) {
+	switch obj := obj.(type) {
+	case *types.Var:
+		if obj.IsField() {
+			names.AddSelector(obj.Name(), newName)
+			return
+		}
+	case *types.Func:
+		if obj.Signature().Recv() != nil {
+			names.AddSelector(obj.Name(), newName)
+			return
+		}
+	}
+	if obj.Parent() != pkg.Types.Scope() {
+		return
+	}
+	names.Package[obj.Name()] = newName
+	if obj.Exported() {
+		if exported[pkg.PkgPath] == nil {
+			exported[pkg.PkgPath] = make(map[string]string)
+		}
+		exported[pkg.PkgPath][obj.Name()] = newName
+	}
+}
+
+// siblingFiles returns the go files of pkg excluded from the build by their
+// _GOOS or _GOARCH suffixes, which are written by writeSiblings.
+func siblingFiles(pkg *packages.Package) (files []string) {
+	for _, gofile := range pkg.IgnoredFiles {
+		base := filepath.Base(gofile)
+		if filepath.Ext(base) != ".go" || !filename.IsPlatform(base) {
+			continue
+		}
+		if isTestFile(gofile) && !loadTests() {
+			continue // Test files are copied or omitted.
+		}
+		files = append(files, gofile)
+	}
+	return
+}
+
+// writeSiblings renames the go files of pkg excluded from the build by their
+// _GOOS or _GOARCH suffixes with names and writes them with write, indexed from index.
+// The names are nil if pkg is copied verbatim, and only the references to the
+// exported declarations of other packages are renamed. The invariants of the files
+// are added to snapshot before renaming.
+func writeSiblings(pkg *packages.Package, names *sibling.Names, exported map[string]map[string]string, trim bool, index int, snapshot invariant.Snapshot, write func(i int, f *ast.File, gofile string) error) error {
+	if names == nil {
+		names = sibling.NewNames()
+	}
+	names.Imports = exported
+	importName := func(importPath string) string {
+		if imported := pkg.Imports[importPath]; imported != nil {
+			return imported.Name
+		}
+		return path.Base(importPath)
+	}
+	for _, gofile := range siblingFiles(pkg) {
+		test := isTestFile(gofile)
+		f, err := parser.ParseFile(pkg.Fset, gofile, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		snapshot.Take(f)
+		for _, p := range sibling.Rename(f, names, importName) {
+			slog.Warn("identifier in platform-specific file may be renamed incorrectly", "pos", pkg.Fset.Position(p.Pos), "name", p.Name, "problem", p.Message)
+		}
+		if trim && (!test || cmdArgs.IncludeTests) {
+			comments.Trim(f)
+		}
+		if err = write(index, f, gofile); err != nil {
+			return err
+		}
+		index++
+	}
+	return nil
+}
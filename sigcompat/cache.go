@@ -0,0 +1,148 @@
+package sigcompat
+
+import (
+	"go/types"
+	"strconv"
+)
+
+// Cache caches the results of [Signatures] by the fingerprints of signatures,
+// so the same comparisons of common signatures, such as String() string and
+// Error() string, are done only once in a run.
+//
+// Only the signatures without type parameters, local defined types, and unexported
+// fields or methods have fingerprints; the others are always compared. The packages of the types are
+// identified by their *types.Package, so a Cache must only be used with types
+// from the same type-checking run, such as the packages loaded together.
+//
+// A Cache is not safe for concurrent use.
+type Cache struct {
+	pkgs    map[*types.Package]int
+	ids     map[string]int           // Fingerprints to their ids.
+	sigs    map[*types.Signature]int // Signatures to the ids of their fingerprints, -1 if none.
+	results map[[2]int]bool          // Pairs of ids, the smaller first.
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		pkgs:    make(map[*types.Package]int),
+		ids:     make(map[string]int),
+		sigs:    make(map[*types.Signature]int),
+		results: make(map[[2]int]bool),
+	}
+}
+
+// Signatures is the same as [Signatures], with the results cached in c.
+// A nil c does not cache.
+func (c *Cache) Signatures(sig1, sig2 *types.Signature) bool {
+	if c == nil {
+		return Signatures(sig1, sig2)
+	}
+	id1, id2 := c.id(sig1), c.id(sig2)
+	if id1 < 0 || id2 < 0 {
+		return Signatures(sig1, sig2)
+	}
+	if id1 == id2 {
+		return true
+	}
+	key := [2]int{min(id1, id2), max(id1, id2)} // Signatures is symmetric.
+	result, ok := c.results[key]
+	if !ok {
+		result = Signatures(sig1, sig2)
+		c.results[key] = result
+	}
+	return result
+}
+
+// id returns the id of the fingerprint of sig, or -1 if sig has no fingerprint.
+func (c *Cache) id(sig *types.Signature) int {
+	if id, ok := c.sigs[sig]; ok {
+		return id
+	}
+	id := -1
+	if fp, ok := c.fingerprint(sig); ok {
+		if id, ok = c.ids[fp]; !ok {
+			id = len(c.ids)
+			c.ids[fp] = id
+		}
+	}
+	c.sigs[sig] = id
+	return id
+}
+
+// fingerprint returns the string of sig ignoring the receiver, with packages
+// qualified by their ids in c. Ok is false if sig has no fingerprint.
+func (c *Cache) fingerprint(sig *types.Signature) (fp string, ok bool) {
+	if !closed(sig.Params()) || !closed(sig.Results()) {
+		return "", false
+	}
+	sig = types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+	return types.TypeString(sig, c.qualify), true
+}
+
+func (c *Cache) qualify(pkg *types.Package) string {
+	id, ok := c.pkgs[pkg]
+	if !ok {
+		id = len(c.pkgs)
+		c.pkgs[pkg] = id
+	}
+	return pkg.Path() + "#" + strconv.Itoa(id)
+}
+
+// closed returns whether t contains no type parameters, no unexported fields or methods,
+// and all the defined types in t are declared at package level, so its string identifies it.
+// The underlying types of defined types are not checked, they are identified by their names.
+func closed(t types.Type) bool {
+	switch t := types.Unalias(t).(type) {
+	case *types.Basic:
+		return true
+	case *types.Pointer:
+		return closed(t.Elem())
+	case *types.Slice:
+		return closed(t.Elem())
+	case *types.Array:
+		return closed(t.Elem())
+	case *types.Map:
+		return closed(t.Key()) && closed(t.Elem())
+	case *types.Chan:
+		return closed(t.Elem())
+	case *types.Tuple:
+		for v := range t.Variables() {
+			if !closed(v.Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Signature:
+		return t.TypeParams() == nil && closed(t.Params()) && closed(t.Results())
+	case *types.Struct:
+		for field := range t.Fields() {
+			if !field.Exported() || !closed(field.Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Interface:
+		if !t.IsMethodSet() {
+			return false // Constraint.
+		}
+		for mtd := range t.Methods() {
+			if !mtd.Exported() || !closed(mtd.Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil && obj.Parent() != obj.Pkg().Scope() {
+			return false // Local type.
+		}
+		for arg := range t.TypeArgs().Types() {
+			if !closed(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false // Type parameters and the types not understood.
+}
@@ -0,0 +1,128 @@
+package sigcompat
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"testing"
+)
+
+func Test_Methods(t *testing.T) {
+	pkg, _ := loadPackage()
+
+	f1 := lookupMethod(pkg, "t1", 0)
+	f2 := lookupMethod(pkg, "t2", 0)
+	f3 := lookupMethod(pkg, "t3", 0)
+	f4 := lookupMethod(pkg, "t4", 0)
+	f5 := lookupMethod(pkg, "t5", 0)
+	f6 := lookupMethod(pkg, "t6", 0)
+	f7 := lookupMethod(pkg, "t7", 0)
+	f8 := lookupMethod(pkg, "t8", 0)
+	f9 := lookupMethod(pkg, "t9", 0)
+	f10 := lookupMethod(pkg, "t10", 0)
+	f11 := lookupMethod(pkg, "t11", 0)
+	f12 := lookupMethod(pkg, "t12", 0)
+	f13 := lookupMethod(pkg, "t13", 0)
+	f14 := lookupMethod(pkg, "t14", 0)
+	f15 := lookupMethod(pkg, "t15", 0)
+	f16 := lookupMethod(pkg, "t16", 0)
+	f17 := lookupMethod(pkg, "t17", 0)
+	f18 := lookupMethod(pkg, "t18", 0)
+	f19 := lookupMethod(pkg, "t19", 0)
+	f20 := lookupMethod(pkg, "t20", 0)
+	f21 := lookupMethod(pkg, "t21", 0)
+	f22 := lookupMethod(pkg, "t22", 0)
+	f23 := lookupMethod(pkg, "t23", 0)
+	f24 := lookupMethod(pkg, "t24", 0)
+	f25 := lookupMethod(pkg, "t25", 0)
+	f26 := lookupMethod(pkg, "t26", 0)
+	f27 := lookupMethod(pkg, "t27", 0)
+	f28 := lookupMethod(pkg, "t28", 0)
+
+	assertImplSameMethod(t, f1, f2, true, "simple match")
+	assertImplSameMethod(t, f1, f3, false, "variadic vs non-variadic")
+	assertImplSameMethod(t, f3, f4, true, "variadic match")
+	assertImplSameMethod(t, f5, f6, false, "defined types are unique")
+	assertImplSameMethod(t, f5, f7, false, "defined types are unique")
+	assertImplSameMethod(t, f6, f7, true, "alias match")
+	assertImplSameMethod(t, f5, f8, false, "defined types are unique")
+	assertImplSameMethod(t, f6, f8, false, "defined types are unique")
+	assertImplSameMethod(t, f1, f9, false, "not satisfies")
+	assertImplSameMethod(t, f10, f11, true, "Type terms intersect")
+	assertImplSameMethod(t, f10, f1, false, "defined types are unique")
+	assertImplSameMethod(t, f12, f1, false, "simple mismatch: argument")
+	assertImplSameMethod(t, f13, f1, false, "simple mismatch: return value")
+	assertImplSameMethod(t, f14, f9, false, "not satisfies")
+	assertImplSameMethod(t, f15, f16, false, "defined types are unique")
+	assertImplSameMethod(t, f1, f17, true, "satisfies")
+	assertImplSameMethod(t, f18, f18, true, "no param nor result")
+	assertImplSameMethod(t, f19, f20, true, "identical structs")
+	assertImplSameMethod(t, f19, f21, false, "tags diff")
+	assertImplSameMethod(t, f1, f21, false, "param diff")
+	assertImplSameMethod(t, f19, f22, true, "potentially identical structs")
+	assertImplSameMethod(t, f19, f23, false, "tags diff")
+	assertImplSameMethod(t, f21, f23, true, "potentially identical structs")
+	assertImplSameMethod(t, f24, f25, true, "potentially identical interfaces")
+	assertImplSameMethod(t, f26, f27, true, "same array length")
+	assertImplSameMethod(t, f26, f28, false, "array length diff")
+
+}
+
+// assertImplSameMethod is a helper for testing Methods.
+func assertImplSameMethod(t *testing.T, mtd1, mtd2 *types.Func, expected bool, msg string) {
+	t.Helper()
+	actual := Methods(mtd1, mtd2)
+	if actual != expected {
+		t.Errorf("Methods got %v, expected %v. %s", actual, expected, msg)
+	}
+}
+
+func lookupMethod(pkg *types.Package, typeName string, mtdIndex int) *types.Func {
+	return pkg.Scope().Lookup(typeName).Type().(*types.Named).Method(mtdIndex)
+}
+
+func loadPackage() (pkg *types.Package, info *types.Info) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/signature.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info = &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, err = conf.Check("signature", fset, []*ast.File{f}, info)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return
+}
+
+func Test_Cache(t *testing.T) {
+	pkg, _ := loadPackage()
+	var methods []*types.Func
+	for i := 1; i <= 28; i++ {
+		methods = append(methods, lookupMethod(pkg, fmt.Sprintf("t%d", i), 0))
+	}
+	cache := NewCache()
+	for range 2 { // The second round is answered by the cache.
+		for _, m1 := range methods {
+			for _, m2 := range methods {
+				sig1, sig2 := m1.Signature(), m2.Signature()
+				if got, want := cache.Signatures(sig1, sig2), Signatures(sig1, sig2); got != want {
+					t.Errorf("%v, %v: got %v, want %v", m1, m2, got, want)
+				}
+			}
+		}
+	}
+	if len(cache.results) == 0 {
+		t.Fatal("nothing cached")
+	}
+
+	// Signatures with type parameters have no fingerprints.
+	if _, ok := cache.fingerprint(lookupMethod(pkg, "t10", 0).Signature()); ok {
+		t.Error("t10.f has type parameters")
+	}
+}
@@ -0,0 +1,16 @@
+// Package lib greets.
+package lib
+
+import _ "embed"
+
+//go:embed format.txt
+var format string
+
+// Greeting returns the greeting to name.
+func Greeting(name string) string {
+	return prefix() + name
+}
+
+func prefix() string {
+	return format
+}
@@ -0,0 +1,9 @@
+package lib
+
+import "testing"
+
+func TestGreeting(t *testing.T) {
+	if got := Greeting("go"); got != "hello, go" {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/ok/lib"
+)
+
+func main() {
+	fmt.Println(lib.Greeting("world"))
+}
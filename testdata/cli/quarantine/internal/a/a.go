@@ -0,0 +1,15 @@
+package a
+
+import "unsafe"
+
+type I interface {
+	Method() int
+}
+
+type Header struct {
+	Size int
+}
+
+func Size(h Header) uintptr {
+	return unsafe.Sizeof(h)
+}
@@ -0,0 +1,17 @@
+package b
+
+import "example.com/quarantine/internal/a"
+
+type T struct{}
+
+func (T) Method() int { return 1 }
+
+type Record struct {
+	Size int
+}
+
+func Use() int {
+	var i a.I = T{}
+	h := a.Header(Record{Size: 2})
+	return i.Method() + int(a.Size(h))
+}
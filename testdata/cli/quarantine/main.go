@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/quarantine/internal/b"
+)
+
+func main() {
+	fmt.Println(b.Use())
+}
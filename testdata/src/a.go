@@ -126,3 +126,13 @@ func init() {
 	_ = v19
 	_ = v20
 }
+
+// apiKey is renamed to a long random name.
+//
+//goingbad:secure
+var apiKey = "key"
+
+//goingbad:secure
+func VerifyKey(key string) bool {
+	return key == apiKey
+}
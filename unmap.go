@@ -0,0 +1,403 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/mkch/gg"
+	"github.com/mkch/goingbad/internal/filename"
+	"github.com/mkch/goingbad/internal/mapping"
+	"github.com/mkch/goingbad/internal/pattern"
+	"github.com/mkch/goingbad/internal/sibling"
+	"golang.org/x/tools/go/packages"
+)
+
+// unmap implements the unmap subcommand, which copies the obfuscated module in current
+// directory with the packages matching patterns restored to their original names by the
+// mapping file written with -map, so a subsystem can be debugged in the obfuscated module
+// without the original source:
+//
+//	goingbad unmap [-map-key file] [-overwrite] -map map.json -o dir pattern...
+//
+// The uses of the restored declarations in the other packages are restored too, and so
+// are the methods of other packages implementing or implemented by restored methods.
+// The other identifiers of the other packages keep their obfuscated names.
+func unmap(args []string) int {
+	flags := flag.NewFlagSet("unmap", flag.ContinueOnError)
+	mapFile := flags.String("map", "", "Path to the mapping file the module is obfuscated with.")
+	keyFile := flags.String("map-key", "", "Path to the file of the key the mapping file is signed with.")
+	out := flags.String("o", "", "Path to the output directory.")
+	overwrite := flags.Bool("overwrite", false, "Overwrite existing output files.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *mapFile == "" || *out == "" || flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: goingbad unmap [-map-key file] [-overwrite] -map map.json -o dir pattern...")
+		return exitUsage
+	}
+	var key []byte
+	if *keyFile != "" {
+		var err error
+		if key, err = mapping.ReadKey(*keyFile); err != nil {
+			slog.Error(err.Error())
+			return exitFailure
+		}
+	}
+	m, err := mapping.Load(*mapFile, key)
+	if err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	if err = writeUnmapped(m, flags.Args(), *out, *overwrite); err != nil {
+		slog.Error(err.Error())
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeUnmapped copies current directory to out, with the packages matching patterns restored by m.
+func writeUnmapped(m *mapping.Map, patterns []string, out string, overwrite bool) error {
+	const mode = packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedCompiledGoFiles |
+		packages.NeedImports |
+		packages.NeedTypes |
+		packages.NeedSyntax |
+		packages.NeedTypesInfo
+	loaded, err := packages.Load(&packages.Config{Mode: mode, Tests: true}, "./...")
+	if err != nil {
+		return err
+	}
+	if n := logPackageErrors(loaded); n > 0 {
+		return fmt.Errorf("%d "+gg.If(n > 1, "errors", "error"), n)
+	}
+
+	// Original names by the positions of definitions.
+	restored := make(map[token.Pos]string)
+	restoredPkgs := make(gg.Set[string])
+	for _, pkg := range loaded {
+		if !slices.ContainsFunc(patterns, func(p string) bool { return pattern.Match(p, pkg.PkgPath, pkg.Dir) }) {
+			continue
+		}
+		restoredPkgs.Add(pkg.PkgPath)
+		r := mapping.NewRestorer(pkg, m.Entries)
+		restore := func(obj types.Object) {
+			name, ok := r.Name(obj)
+			if !ok {
+				slog.Warn("original name is ambiguous, identifier is not restored", "pos", pkg.Fset.Position(obj.Pos()), "name", obj.Name())
+			} else if name != obj.Name() {
+				restored[obj.Pos()] = name
+			}
+		}
+		for _, obj := range pkg.TypesInfo.Defs {
+			if obj != nil {
+				restore(obj)
+			}
+		}
+		// The symbolic variables of type switches are defined implicitly in each clause.
+		for node, obj := range pkg.TypesInfo.Implicits {
+			if _, ok := node.(*ast.CaseClause); ok {
+				restore(obj)
+			}
+		}
+	}
+	if len(restoredPkgs) == 0 {
+		return errors.New("no package matches the patterns")
+	}
+	relateMethods(loaded, restored)
+	// Embedded fields are named after their types.
+	for _, pkg := range loaded {
+		for _, obj := range pkg.TypesInfo.Defs {
+			if v, ok := obj.(*types.Var); ok && v.Embedded() {
+				if named := embeddedType(v.Type()); named != nil {
+					if name, ok := restored[named.Obj().Pos()]; ok {
+						restored[v.Pos()] = name
+					}
+				}
+			}
+		}
+	}
+
+	// Identifiers are renamed in the source files instead of printing the syntax trees,
+	// whose positions do not leave room for the original names, which are usually longer.
+	renames := make(map[string]map[int]identRename)
+	addRename := func(fset *token.FileSet, id *ast.Ident, name string) {
+		position := fset.PositionFor(id.Pos(), false)
+		if renames[position.Filename] == nil {
+			renames[position.Filename] = make(map[int]identRename)
+		}
+		renames[position.Filename][position.Offset] = identRename{id.Name, name}
+	}
+	pkgs := filterTestPackages(loaded)
+	// The files in the source tree. Compiled cgo files are not.
+	sources := make(gg.Set[string])
+	for _, pkg := range pkgs {
+		for _, file := range slices.Concat(pkg.GoFiles, pkg.IgnoredFiles) {
+			sources.Add(file)
+		}
+		for id := range pkg.TypesInfo.Defs {
+			if name, ok := restored[id.Pos()]; ok {
+				addRename(pkg.Fset, id, name)
+			}
+		}
+		for id, obj := range pkg.TypesInfo.Uses {
+			if name, ok := restored[origin(obj).Pos()]; ok {
+				addRename(pkg.Fset, id, name)
+			}
+		}
+	}
+	if err = restoreSiblings(pkgs, restored, restoredPkgs, addRename); err != nil {
+		return err
+	}
+	// Contents of the rewritten files by their paths.
+	rewritten := make(map[string][]byte)
+	for file, fileRenames := range renames {
+		if !sources.Contains(file) {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if rewritten[file], err = renameIdents(src, fileRenames); err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+	}
+	return copyUnmapped(out, rewritten, overwrite)
+}
+
+// identRename renames an identifier from old to new.
+type identRename struct{ old, new string }
+
+// renameIdents returns src with the identifiers at the offsets of renames renamed.
+func renameIdents(src []byte, renames map[int]identRename) ([]byte, error) {
+	// Edit from the end, so the offsets of the other edits are not changed.
+	for _, offset := range slices.Backward(slices.Sorted(maps.Keys(renames))) {
+		r := renames[offset]
+		end := offset + len(r.old)
+		if end > len(src) || string(src[offset:end]) != r.old {
+			return nil, fmt.Errorf("cannot find identifier %v at offset %v", r.old, offset)
+		}
+		src = slices.Replace(src, offset, end, []byte(r.new)...)
+	}
+	return src, nil
+}
+
+// origin returns the generic object obj is instantiated from, or obj.
+func origin(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.Var:
+		return obj.Origin()
+	}
+	return obj
+}
+
+// embeddedType returns the named type of embedded field type t, or nil.
+func embeddedType(t types.Type) *types.Named {
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, _ := types.Unalias(t).(*types.Named)
+	return named
+}
+
+// relateMethods adds to restored the methods of pkgs which must have the same names as
+// restored methods: the methods implementing restored interface methods, and the interface
+// methods implemented by restored methods, in the packages and the packages they import.
+func relateMethods(pkgs []*packages.Package, restored map[token.Pos]string) {
+	// Methods related to each other are in the same set.
+	parents := make(map[token.Pos]token.Pos)
+	var find func(pos token.Pos) token.Pos
+	find = func(pos token.Pos) token.Pos {
+		parent, ok := parents[pos]
+		if !ok || parent == pos {
+			return pos
+		}
+		root := find(parent)
+		parents[pos] = root
+		return root
+	}
+	union := func(a, b token.Pos) {
+		if a, b = find(a), find(b); a != b {
+			parents[a] = b
+		}
+	}
+	// Named types declared in the package scope of pkg and the packages it imports,
+	// which are loaded from source. The others are not obfuscated.
+	visible := func(pkg *packages.Package) (named []*types.Named) {
+		packages.Visit([]*packages.Package{pkg}, func(pkg *packages.Package) bool {
+			if len(pkg.Syntax) == 0 {
+				return false
+			}
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				if tn, ok := scope.Lookup(name).(*types.TypeName); ok && !tn.IsAlias() {
+					if t, ok := tn.Type().(*types.Named); ok && t.TypeParams().Len() == 0 {
+						named = append(named, t)
+					}
+				}
+			}
+			return true
+		}, nil)
+		return
+	}
+	for _, pkg := range pkgs {
+		named := visible(pkg)
+		for _, i := range named {
+			iface, ok := i.Underlying().(*types.Interface)
+			if !ok || iface.NumMethods() == 0 {
+				continue
+			}
+			for _, t := range named {
+				if t == i || !types.Implements(t, iface) && (types.IsInterface(t) || !types.Implements(types.NewPointer(t), iface)) {
+					continue
+				}
+				for method := range iface.Methods() {
+					if obj, _, _ := types.LookupFieldOrMethod(t, true, method.Pkg(), method.Name()); obj != nil {
+						union(method.Pos(), origin(obj).Pos())
+					}
+				}
+			}
+		}
+	}
+	groups := make(map[token.Pos][]token.Pos)
+	for pos := range parents {
+		root := find(pos)
+		groups[root] = append(groups[root], pos)
+	}
+	for root, group := range groups {
+		group = append(group, root)
+		slices.Sort(group)
+		i := slices.IndexFunc(group, func(pos token.Pos) bool { _, ok := restored[pos]; return ok })
+		if i < 0 {
+			continue
+		}
+		name := restored[group[i]]
+		for _, pos := range group {
+			restored[pos] = name
+		}
+	}
+}
+
+// restoreSiblings restores the go files of pkgs excluded from the build by their _GOOS or _GOARCH
+// suffixes with rename. The files of the packages in restoredPkgs are restored, and the references
+// to the exported declarations of restoredPkgs in all the files.
+func restoreSiblings(pkgs []*packages.Package, restored map[token.Pos]string, restoredPkgs gg.Set[string],
+	rename func(fset *token.FileSet, id *ast.Ident, name string)) error {
+	// Original names of the exported declarations of restored packages by import paths.
+	exported := make(map[string]map[string]string)
+	names := make(map[*packages.Package]*sibling.Names)
+	for _, pkg := range pkgs {
+		if !restoredPkgs.Contains(pkg.PkgPath) {
+			continue
+		}
+		names[pkg] = sibling.NewNames()
+		for _, obj := range pkg.TypesInfo.Defs {
+			if obj == nil {
+				continue
+			}
+			if name, ok := restored[obj.Pos()]; ok {
+				addSiblingName(pkg, names[pkg], exported, obj, name)
+			}
+		}
+	}
+	done := make(gg.Set[string]) // Files shared by the test variants.
+	for _, pkg := range pkgs {
+		n := names[pkg]
+		if n == nil {
+			n = sibling.NewNames()
+		}
+		n.Imports = exported
+		importName := func(importPath string) string {
+			if imported := pkg.Imports[importPath]; imported != nil {
+				return imported.Name
+			}
+			return path.Base(importPath)
+		}
+		for _, gofile := range pkg.IgnoredFiles {
+			if done.Contains(gofile) || filepath.Ext(gofile) != ".go" || !filename.IsPlatform(filepath.Base(gofile)) {
+				continue
+			}
+			done.Add(gofile)
+			f, err := parser.ParseFile(pkg.Fset, gofile, nil, 0)
+			if err != nil {
+				return err
+			}
+			// The names of the identifiers before renaming.
+			old := make(map[*ast.Ident]string)
+			ast.Inspect(f, func(node ast.Node) bool {
+				if id, ok := node.(*ast.Ident); ok {
+					old[id] = id.Name
+				}
+				return true
+			})
+			for _, p := range sibling.Rename(f, n, importName) {
+				slog.Warn("identifier in platform-specific file may be restored incorrectly", "pos", pkg.Fset.Position(p.Pos), "name", p.Name, "problem", p.Message)
+			}
+			for id, name := range old {
+				if id.Name != name {
+					newName := id.Name
+					id.Name = name
+					rename(pkg.Fset, id, newName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// copyUnmapped copies the regular files in current directory to out, with the contents of
+// the files in rewritten replaced. Directory out and the directories of version control
+// systems are skipped.
+func copyUnmapped(out string, rewritten map[string][]byte, overwrite bool) error {
+	src := gg.Must(filepath.Abs(""))
+	out = gg.Must(filepath.Abs(out))
+	return filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file == out || file != src && slices.Contains([]string{".git", ".hg", ".svn"}, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, ok := rewritten[file]
+		if !ok {
+			if data, err = os.ReadFile(file); err != nil {
+				return err
+			}
+		}
+		dest := filepath.Join(out, gg.Must(filepath.Rel(src, file)))
+		if err = os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+			return err
+		}
+		w, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|gg.If(overwrite, os.O_TRUNC, os.O_EXCL), info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return errors.Join(err, w.Close())
+	})
+}
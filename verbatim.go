@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mkch/gg"
+	"golang.org/x/tools/go/packages"
+)
+
+// writeVerbatim writes the go files of pkg, which is not obfuscated, into destDir.
+func writeVerbatim(pkg *packages.Package, destDir string, renamedExports map[token.Pos]string) error {
+	for _, gofile := range pkg.GoFiles {
+		src, err := verbatimFile(pkg, gofile, renamedExports)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destDir, filepath.Base(gofile))
+		slog.Info("writing go file verbatim...\t", "path", dest)
+		var w io.WriteCloser
+		if w, err = createFile(dest); err != nil {
+			return err
+		}
+		_, err = w.Write(src)
+		if err = errors.Join(err, w.Close()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verbatimFile returns the content of gofile, a source file of pkg, with only the
+// references to the renamed exports of other packages rewritten. The syntax of pkg
+// is not used, because it may be partially renamed, or processed by cgo.
+func verbatimFile(pkg *packages.Package, gofile string, renamedExports map[token.Pos]string) ([]byte, error) {
+	src, err := os.ReadFile(gofile)
+	if err != nil {
+		return nil, err
+	}
+	lines := []int{0} // Offsets of the lines.
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	type edit struct {
+		offset, end int
+		name        string
+	}
+	var edits []edit
+	for id, use := range pkg.TypesInfo.Uses {
+		newName, ok := renamedExports[use.Pos()]
+		if !ok {
+			continue
+		}
+		// The files processed by cgo refer to their sources with line directives.
+		adjusted := !slices.Contains(pkg.GoFiles, pkg.Fset.File(id.Pos()).Name())
+		position := pkg.Fset.PositionFor(id.Pos(), adjusted)
+		if filepath.Clean(position.Filename) != filepath.Clean(gofile) {
+			continue
+		}
+		// The name of id may have been changed, but not the name of use.
+		var offset, end int
+		if position.Line <= len(lines) {
+			offset = lines[position.Line-1] + position.Column - 1
+			end = offset + len(use.Name())
+		}
+		if end == 0 || end > len(src) || !bytes.Equal(src[offset:end], []byte(use.Name())) {
+			return nil, fmt.Errorf("%v: cannot find the reference to %v renamed", position, use.Name())
+		}
+		edits = append(edits, edit{offset, end, newName})
+	}
+	// Edit from the end, so the offsets of the other edits are not changed.
+	slices.SortFunc(edits, func(a, b edit) int { return cmp.Compare(b.offset, a.offset) })
+	edits = slices.CompactFunc(edits, func(a, b edit) bool { return a.offset == b.offset })
+	for _, e := range edits {
+		src = slices.Replace(src, e.offset, e.end, []byte(e.name)...)
+	}
+	return src, nil
+}
+
+// memberNames returns the names of the exported methods and fields declared in pkg,
+// including the methods of interfaces. The packages copied verbatim keep these names,
+// so the other packages must keep the names of their methods and fields matching them,
+// such as the methods implementing the interfaces of pkg.
+func memberNames(pkg *packages.Package) gg.Set[string] {
+	result := make(gg.Set[string])
+	for id, obj := range pkg.TypesInfo.Defs {
+		if !id.IsExported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Var:
+			if obj.IsField() {
+				result.Add(id.Name)
+			}
+		case *types.Func:
+			if obj.Signature().Recv() != nil {
+				result.Add(id.Name)
+			}
+		}
+	}
+	return result
+}
+
+// dependencyOrder returns pkgs ordered so that every package comes after the packages it imports.
+func dependencyOrder(pkgs []*packages.Package) (result []*packages.Package) {
+	included := make(gg.Set[*packages.Package])
+	for _, pkg := range pkgs {
+		included.Add(pkg)
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if included.Contains(pkg) {
+			result = append(result, pkg)
+		}
+	})
+	return
+}
@@ -0,0 +1,35 @@
+package whatif
+
+var x int
+
+func f() int {
+	y := 1
+	return x + y
+}
+
+func g() int {
+	y := 2
+	z := y
+	return x + z
+}
+
+type T struct {
+	a, b int
+}
+
+type M int
+
+func (M) m() {}
+func (M) n() {}
+
+type E int
+
+type S struct {
+	E
+	F int
+}
+
+type P struct {
+	M
+	o int
+}
@@ -0,0 +1,81 @@
+// Package whatif reports whether renaming a definition of a package would change the
+// behavior of the program, with the same checks goingbad does before renaming, and
+// the conflicts which prevent the renaming.
+package whatif
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+
+	"github.com/mkch/goingbad/internal/renamer/conflict"
+	"github.com/mkch/goingbad/internal/renamer/scope"
+	"github.com/mkch/goingbad/internal/renamer/selection"
+	"github.com/mkch/iter2"
+	"golang.org/x/tools/go/packages"
+)
+
+// ConflictKind is the kind of a [Conflict].
+type ConflictKind = conflict.Kind
+
+const (
+	// DefConflict is that the new name is already defined in the scope of
+	// the definition, or the definition would shadow the uses of the new name.
+	DefConflict = conflict.DefConflict
+	// UseConflict is that a use of the definition would refer to another
+	// declaration of the new name.
+	UseConflict = conflict.UseConflict
+	// SelectorConflict is that the new name of a field or method clashes with
+	// a field or method of the types it belongs to or is promoted to.
+	SelectorConflict = conflict.SelectorConflict
+	// EmbeddedConflict is that the new name of a type, which is also the name of
+	// the fields embedding it, clashes with a field or method of the embedding structs.
+	EmbeddedConflict = conflict.EmbeddedConflict
+)
+
+// Conflict is a reason why renaming a definition would change the behavior of the program.
+// The Pos of a SelectorConflict is the position of the clashing field or method,
+// and the Type is its receiver or struct type.
+type Conflict = conflict.Conflict
+
+// Analysis answers whether the definitions of a package can be renamed.
+// It reflects the package before any renaming.
+type Analysis struct {
+	pkg       *packages.Package
+	conflicts *conflict.Checker
+	defs      map[token.Pos]*ast.Ident
+}
+
+// NewAnalysis creates an Analysis of pkg.
+func NewAnalysis(pkg *packages.Package) *Analysis {
+	a := &Analysis{pkg: pkg, defs: make(map[token.Pos]*ast.Ident)}
+	methodGroup := maps.Collect(iter2.Map2(
+		maps.All(selection.GroupMethods(pkg.TypesInfo.Defs, nil)),
+		func(k *types.Func, v []selection.Method) (token.Pos, []selection.Method) {
+			return k.Pos(), v
+		}))
+	_, info := scope.PackageScope(pkg.Types, pkg.TypesInfo)
+	a.conflicts = &conflict.Checker{Pkg: pkg.Types, Info: info, Sel: selection.New(pkg), MethodGroup: methodGroup}
+	for id := range pkg.TypesInfo.Defs {
+		a.defs[id.Pos()] = id
+	}
+	return a
+}
+
+// WhatIf returns whether renaming the identifier defined at def to newName keeps the
+// behavior of the program, and the conflicts if it does not.
+// If def is not the position of a definition, the result is (false, nil).
+func (a *Analysis) WhatIf(def token.Pos, newName string) (ok bool, conflicts []Conflict) {
+	id := a.defs[def]
+	if id == nil {
+		return false, nil
+	}
+	if obj := a.pkg.TypesInfo.Defs[id]; obj != nil && obj.Parent() == nil { // methods and struct fields.
+		conflicts = slices.Collect(a.conflicts.FieldMethod(id, newName))
+	} else {
+		conflicts = slices.Collect(a.conflicts.Scoped(id, newName))
+	}
+	return len(conflicts) == 0, conflicts
+}
@@ -0,0 +1,98 @@
+package whatif
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func Test_Analysis_WhatIf(t *testing.T) {
+	pkg, err := loadPackage("testdata/whatif.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// position returns the position of the nth identifier name in the file, counting from 0.
+	position := func(name string, n int) token.Pos {
+		var result token.Pos
+		ast.Inspect(pkg.Syntax[0], func(node ast.Node) bool {
+			if id, ok := node.(*ast.Ident); ok && id.Name == name {
+				if n == 0 {
+					result = id.Pos()
+				}
+				n--
+			}
+			return result == token.NoPos
+		})
+		return result
+	}
+	// typeOf returns the type of the package level declaration name.
+	typeOf := func(name string) types.Type {
+		return pkg.Types.Scope().Lookup(name).Type()
+	}
+	a := NewAnalysis(pkg)
+
+	tests := []struct {
+		name      string
+		def       token.Pos
+		newName   string
+		ok        bool
+		conflicts []Conflict
+	}{
+		{"ok", position("y", 0), "z", true, nil},
+		{"shadow use", position("y", 0), "x", false, []Conflict{{Kind: DefConflict, Pos: position("y", 0)}, {Kind: UseConflict, Pos: position("y", 1)}}},
+		{"redefined", position("y", 2), "z", false, []Conflict{{Kind: DefConflict, Pos: position("z", 0)}, {Kind: UseConflict, Pos: position("y", 3)}}},
+		{"use refers to other", position("x", 0), "y", false, []Conflict{{Kind: UseConflict, Pos: position("x", 1)}, {Kind: UseConflict, Pos: position("x", 2)}}},
+		{"field", position("a", 0), "b", false, []Conflict{{Kind: SelectorConflict, Pos: position("b", 0), Type: typeOf("T").Underlying()}}},
+		{"method", position("m", 0), "n", false, []Conflict{{Kind: SelectorConflict, Pos: position("n", 0), Type: typeOf("M")}}},
+		{"promoted", position("m", 0), "o", false, []Conflict{{Kind: SelectorConflict, Pos: position("o", 0), Type: typeOf("P").Underlying()}}},
+		{"embedded", position("E", 0), "F", false, []Conflict{{Kind: EmbeddedConflict, Pos: position("E", 0)}}},
+		{"not a definition", position("x", 1), "a", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, conflicts := a.WhatIf(tt.def, tt.newName)
+			if ok != tt.ok {
+				t.Errorf("want ok %v, got %v", tt.ok, ok)
+			}
+			if !slices.Equal(conflicts, tt.conflicts) {
+				t.Errorf("want conflicts %v, got %v", tt.conflicts, conflicts)
+			}
+		})
+	}
+}
+
+func loadPackage(filename string) (*packages.Package, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: importer.Default()}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+	if err != nil {
+		return nil, err
+	}
+	return &packages.Package{
+		Name:      pkg.Name(),
+		PkgPath:   pkg.Path(),
+		Fset:      fset,
+		Syntax:    []*ast.File{f},
+		Types:     pkg,
+		TypesInfo: info,
+	}, nil
+}